@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumSuffix is appended to the output path to name the -with-hash sidecar file.
+const checksumSuffix = ".sha256"
+
+// fileSHA256 returns the SHA-256 digest of the file at path.
+func fileSHA256(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// writeChecksum reports the SHA-256 digest of the output, in the format of sha256sum,
+// allowing "sha256sum -c" checks: to a sidecar file next to the output file if there is one,
+// or to the standard error if the output went to the standard output.
+func writeChecksum(sum []byte, output string) error {
+	if output == "" {
+		_, err := fmt.Fprintf(os.Stderr, "%x  -\n", sum)
+		return err
+	}
+	line := fmt.Sprintf("%x  %s\n", sum, filepath.Base(output))
+	return os.WriteFile(output+checksumSuffix, []byte(line), 0666)
+}
+
+// fetchChecksum downloads the SHA-256 digest published at url in the format of sha256sum,
+// of which only the digest starting the first line is used.
+func (f Fetcher) fetchChecksum(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := f.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP error getting registry checksum: %d %s", res.StatusCode, res.Status)
+	}
+	line, err := bufio.NewReader(io.LimitReader(res.Body, 4096)).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	digest, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+	sum, err := hex.DecodeString(digest)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("invalid SHA-256 checksum %q", digest)
+	}
+	return sum, nil
+}
+
+// verifyChecksum checks that sum is the SHA-256 digest of the registry published at
+// f.ChecksumURL, if any.
+func (f Fetcher) verifyChecksum(ctx context.Context, sum []byte) error {
+	if f.ChecksumURL == "" {
+		return nil
+	}
+	want, err := f.fetchChecksum(ctx, f.ChecksumURL)
+	if err != nil {
+		return fmt.Errorf("failed fetching registry checksum: %w", err)
+	}
+	if !bytes.Equal(sum, want) {
+		return fmt.Errorf("registry checksum mismatch: SHA-256 is %x, but %s has %x", sum, f.ChecksumURL, want)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteChecksum(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"registry", testRegistry("2023-08-02")},
+		{"empty", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := writeTemp(t, "registry.yaml", test.content)
+			sum, err := fileSHA256(output)
+			if err != nil {
+				t.Fatalf("fileSHA256() failed: %v", err)
+			}
+			if err = writeChecksum(sum, output); err != nil {
+				t.Fatalf("writeChecksum() failed: %v", err)
+			}
+			got, err := os.ReadFile(output + checksumSuffix)
+			// The sidecar has the format of sha256sum, with a digest recomputed from the output.
+			if want := fmt.Sprintf("%x  registry.yaml\n", sha256.Sum256([]byte(test.content))); err != nil || string(got) != want {
+				t.Errorf("sidecar holds %q, %v, want %q", got, err, want)
+			}
+		})
+	}
+}
+
+func TestFetcher_downloadAny_checksum(t *testing.T) {
+	body := testRegistry("2023-08-02")
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte(body)))
+	tests := []struct {
+		name     string
+		checksum string // Empty for no -checksum-url.
+		wantErr  string
+	}{
+		{"matching", sum + "  language-subtag-registry\n", ""},
+		{"bare digest", sum, ""},
+		{"mismatching", strings.Repeat("0", 64) + "  language-subtag-registry\n", "registry checksum mismatch"},
+		{"invalid", "not a digest\n", "invalid SHA-256 checksum"},
+		{"no checksum", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path == "/registry.sha256" {
+					io.WriteString(w, test.checksum)
+					return
+				}
+				io.WriteString(w, body)
+			}))
+			defer srv.Close()
+			f, cache := newTestFetcher(t)
+			if test.checksum != "" {
+				f.ChecksumURL = srv.URL + "/registry.sha256"
+			}
+			err := f.downloadAny(context.Background(), []string{srv.URL}, cache, false)
+			got, _ := os.ReadFile(cache.Path)
+			if test.wantErr == "" {
+				if err != nil || string(got) != body {
+					t.Errorf("downloadAny() = %v, caching %q, want %q", err, got, body)
+				}
+				return
+			}
+			// A rejected download is not cached.
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) || len(got) != 0 {
+				t.Errorf("downloadAny() = %v, caching %q, want error %q and no cache", err, got, test.wantErr)
+			}
+		})
+	}
+}
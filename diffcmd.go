@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+// CommandDiff is the command comparing two registry files, instead of emitting a registry.
+const CommandDiff = "diff"
+
+// runDiff implements the "diff OLD NEW" command, writing the registry.Patch from the OLD
+// to the NEW registry file to w as indented JSON, with map keys sorted for stable output.
+func runDiff(w io.Writer, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: diff OLD NEW")
+	}
+	var rs [2]*registry.Registry
+	for i, path := range args {
+		r, err := readRegistry(path)
+		if err != nil {
+			return fmt.Errorf("failed reading %s: %w", path, err)
+		}
+		rs[i] = r
+	}
+	je := json.NewEncoder(w)
+	je.SetIndent("", "  ")
+	return je.Encode(registry.Diff(*rs[0], *rs[1]))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+func TestRunDiff(t *testing.T) {
+	const (
+		head = "File-Date: 2023-08-02\n"
+		iw   = "%%\nType: language\nSubtag: iw\nDescription: Hebrew\nAdded: 2005-10-16\n"
+		fr   = "%%\nType: language\nSubtag: fr\nDescription: French\nAdded: 2005-10-16\n"
+		de   = "%%\nType: region\nSubtag: DE\nDescription: Germany\nAdded: 2005-10-16\n"
+	)
+	older := writeTemp(t, "old.txt", "File-Date: 2023-01-01\n"+iw+de)
+	newer := writeTemp(t, "new.txt", head+iw+"Deprecated: 1989-01-01\nPreferred-Value: he\n"+fr)
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string // Op, type, and key of each change.
+		wantErr string
+	}{
+		{"changes", []string{older, newer}, []string{"remove region DE", "modify language iw", "add language fr"}, ""},
+		{"unchanged", []string{newer, newer}, nil, ""},
+		{"missing argument", []string{older}, nil, "usage: diff OLD NEW"},
+		{"missing file", []string{older, newer + ".missing"}, nil, "failed reading " + newer + ".missing"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := runDiff(&buf, test.args)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("runDiff() = %v, want error %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runDiff() = %v", err)
+			}
+			var p registry.Patch
+			if err = json.Unmarshal(buf.Bytes(), &p); err != nil {
+				t.Fatalf("invalid JSON output: %v\n%s", err, buf.String())
+			}
+			var got []string
+			for _, c := range p.Changes {
+				got = append(got, c.Op+" "+c.Type.String()+" "+c.Key)
+			}
+			if !slices.Equal(got, test.want) || p.FileDate.String() != "2023-08-02" {
+				t.Errorf("runDiff() = %s changes %q, want 2023-08-02 changes %q", p.FileDate, got, test.want)
+			}
+			// The output is stable, to be committed.
+			var again bytes.Buffer
+			if err = runDiff(&again, test.args); err != nil || again.String() != buf.String() {
+				t.Errorf("runDiff() again = %v, output changed:\n%s\n%s", err, buf.String(), again.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+// DefaultTimeout bounds the requests of a Fetcher without a Client, including reading the response.
+const DefaultTimeout = 30 * time.Second
+
+// Fetcher downloads the registry over HTTP.
+type Fetcher struct {
+	// Client performs the requests, to configure timeouts, proxies, or TLS.
+	// Nil means a client with DefaultTimeout.
+	Client *http.Client
+
+	// StatePath names the files keeping the download state, with partialSuffix for
+	// partial downloads and metaSuffix for the validators of the cached registry,
+	// both suffixes for those of the partial download, like the path of the cache file.
+	StatePath string
+
+	// URLs lists the locations of the registry, like mirrors, from which downloads are attempted
+	// in order until one succeeds. Empty means only registry.Url.
+	URLs []string
+
+	// MaxAge is the File-Date age after which a cached registry is stale and downloaded again.
+	// Zero means cached registries never become stale.
+	MaxAge time.Duration
+
+	// Refresh downloads the registry again even if a cached one is still fresh.
+	Refresh bool
+
+	// Offline never downloads the registry, using a cached registry even if it is stale,
+	// and failing if there is none, for air-gapped environments.
+	Offline bool
+
+	// ChecksumURL is the location of the SHA-256 checksum of the registry, in the format of sha256sum,
+	// against which downloads are verified before being accepted. Empty means no verification,
+	// since IANA publishes no checksum, but mirrors might.
+	ChecksumURL string
+}
+
+// client returns the Client to use, applying the default.
+func (f Fetcher) client() *http.Client {
+	if f.Client == nil {
+		return &http.Client{Timeout: DefaultTimeout}
+	}
+	return f.Client
+}
+
+// sourceURLs returns the URLs to download the registry from, applying the default.
+func (f Fetcher) sourceURLs() []string {
+	if len(f.URLs) == 0 {
+		return []string{registry.Url}
+	}
+	return f.URLs
+}
+
+// partialSuffix is appended to Fetcher.StatePath to name the file receiving a download in progress.
+const partialSuffix = ".part"
+
+// metaSuffix is appended to Fetcher.StatePath to name the file holding the cacheMeta of the cached registry.
+const metaSuffix = ".meta"
+
+// errNotModified is returned by download when the server reports the cached registry as current.
+var errNotModified = errors.New("registry not modified")
+
+// cacheMeta holds the validators of the cached registry, as served with it, for conditional requests,
+// and the URL it was downloaded from.
+type cacheMeta struct {
+	ETag         string `yaml:"etag,omitempty"`
+	LastModified string `yaml:"last-modified,omitempty"`
+	URL          string `yaml:"url,omitempty"`
+}
+
+// ifRange returns the validator to send in an If-Range header to resume a download served
+// with meta: its ETag unless weak, which If-Range does not allow, or else its Last-Modified date.
+func (m cacheMeta) ifRange() string {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+// readMeta reads the cacheMeta sidecar file, if any.
+func readMeta(path string) (cacheMeta, error) {
+	var meta cacheMeta
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return meta, err
+	}
+	err = yaml.Unmarshal(bs, &meta)
+	return meta, err
+}
+
+// writeMeta writes the cacheMeta sidecar file.
+func writeMeta(path string, meta cacheMeta) error {
+	bs, err := yaml.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, bs, 0666)
+}
+
+// downloadAny downloads the registry to the cache from the first of urls to succeed,
+// logging the failures of the previous ones.
+//
+// With conditional, the requests carry the validators of the cached registry, and a
+// 304 Not Modified response leaves the cache as it is.
+func (f Fetcher) downloadAny(ctx context.Context, urls []string, cache registry.Cache, conditional bool) error {
+	part, metaPath := f.StatePath+partialSuffix, f.StatePath+metaSuffix
+	var prev cacheMeta
+	if conditional {
+		var err error
+		if prev, err = readMeta(metaPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Ignoring invalid cache metadata: %v", err)
+		}
+	}
+	var errs []error
+	for i, url := range urls {
+		meta, err := f.download(ctx, url, part, prev)
+		if errors.Is(err, errNotModified) {
+			log.Printf("Cached registry is current")
+			removePart(part)
+			return nil
+		}
+		if err == nil {
+			if err = storePart(cache, part); err != nil {
+				return err
+			}
+			meta.URL = url
+			if err = writeMeta(metaPath, meta); err != nil {
+				log.Printf("Failed writing cache metadata: %v", err)
+			}
+			return nil
+		}
+		log.Printf("Failed downloading registry from %s: %v", url, err)
+		errs = append(errs, fmt.Errorf("%s: %w", url, err))
+		if i < len(urls)-1 {
+			// Do not resume a download from another source, which could serve another version.
+			removePart(part)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// storePart stores a complete download in the cache, then removes it.
+func storePart(cache registry.Cache, part string) error {
+	f, err := os.Open(part)
+	if err != nil {
+		return err
+	}
+	defer removePart(part)
+	defer f.Close()
+	if err = cache.Store(f); err != nil {
+		return fmt.Errorf("failed storing registry in cache: %w", err)
+	}
+	return nil
+}
+
+// source describes where the cached registry comes from: the URL it was downloaded from,
+// as recorded in its cacheMeta, or else the cache itself.
+func (f Fetcher) source(cache registry.Cache) string {
+	if meta, err := readMeta(f.StatePath + metaSuffix); err == nil && meta.URL != "" {
+		return meta.URL
+	}
+	return fmt.Sprint(cache)
+}
+
+// removePart removes a partial download and its validators.
+func removePart(part string) {
+	os.Remove(part)
+	os.Remove(part + metaSuffix)
+}
+
+// download fetches the registry at url into the part file, only returning without error
+// once it is complete, parses with registry.Parse, and matches the ChecksumURL if any,
+// with the validators served with it.
+//
+// The request is conditional on the prev validators, if any, returning errNotModified
+// if the server reports the registry as unchanged.
+//
+// When a previous interrupted download left a partial file, download attempts to resume it
+// with a Range request, conditional with If-Range on the validators stored with the partial file,
+// so that the server sends the whole registry instead if it changed since. It falls back
+// to a full download if the server does not return the expected 206 Partial Content,
+// and does not resume partial files lacking validators.
+func (f Fetcher) download(ctx context.Context, url, part string, prev cacheMeta) (cacheMeta, error) {
+	var meta cacheMeta
+	pf, err := os.OpenFile(part, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return meta, fmt.Errorf("failed opening partial download file: %w", err)
+	}
+	defer pf.Close()
+	offset, err := pf.Seek(0, io.SeekEnd)
+	if err != nil {
+		return meta, fmt.Errorf("failed seeking partial download file: %w", err)
+	}
+	var validator string
+	if offset > 0 {
+		if partMeta, err := readMeta(part + metaSuffix); err == nil {
+			validator = partMeta.ifRange()
+		}
+		if validator == "" {
+			log.Printf("Not resuming a partial download without validators")
+			offset = 0
+		}
+	}
+
+	res, err := f.get(ctx, url, offset, validator, prev)
+	if err != nil {
+		return meta, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotModified {
+		return prev, errNotModified
+	}
+	if offset > 0 && !resumes(res, offset) {
+		if res.StatusCode != http.StatusOK {
+			// Ranges are not supported for this file: start over with a plain request.
+			res.Body.Close()
+			if res, err = f.get(ctx, url, 0, "", prev); err != nil {
+				return meta, err
+			}
+			defer res.Body.Close()
+			if res.StatusCode == http.StatusNotModified {
+				return prev, errNotModified
+			}
+		}
+		offset = 0
+	}
+	meta = cacheMeta{ETag: res.Header.Get("ETag"), LastModified: res.Header.Get("Last-Modified")}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		return meta, fmt.Errorf("HTTP error getting fresh registry: %d %s", res.StatusCode, res.Status)
+	}
+	if offset > 0 {
+		log.Printf("Resuming download at byte %d", offset)
+	} else if err = pf.Truncate(0); err != nil {
+		return meta, fmt.Errorf("failed resetting partial download file: %w", err)
+	} else if err = writeMeta(part+metaSuffix, meta); err != nil {
+		// Without validators, the partial file will not be resumed, but the download may still succeed.
+		log.Printf("Failed writing partial download metadata: %v", err)
+	}
+	if _, err = pf.Seek(offset, io.SeekStart); err != nil {
+		return meta, fmt.Errorf("failed seeking partial download file: %w", err)
+	}
+	written, err := io.Copy(pf, decodedBody(res))
+	if err != nil {
+		// Keep the partial file for the next attempt to resume.
+		return meta, fmt.Errorf("failed writing partial download file: %w", err)
+	}
+	log.Printf("Downloaded %d bytes", written)
+
+	if _, err = pf.Seek(0, io.SeekStart); err != nil {
+		return meta, fmt.Errorf("failed rewinding partial download file: %w", err)
+	}
+	if _, err = registry.Parse(pf); err != nil {
+		pf.Close()
+		removePart(part)
+		return meta, fmt.Errorf("downloaded registry is invalid: %w", err)
+	}
+	if err = pf.Close(); err != nil {
+		return meta, fmt.Errorf("failed closing partial download file: %w", err)
+	}
+	sum, err := fileSHA256(part)
+	if err == nil {
+		err = f.verifyChecksum(ctx, sum)
+	}
+	if err != nil {
+		removePart(part)
+		return meta, err
+	}
+	return meta, nil
+}
+
+// get requests url, asking for the bytes from offset onwards when offset is positive,
+// if the registry still matches the ifRange validator, and conditionally on the prev validators, if any.
+func (f Fetcher) get(ctx context.Context, url string, offset int64, ifRange string, prev cacheMeta) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", ifRange)
+	}
+	if prev.ETag != "" {
+		req.Header.Set("If-None-Match", prev.ETag)
+	}
+	if prev.LastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.LastModified)
+	}
+	return f.client().Do(req)
+}
+
+// resumes reports whether res is a partial response starting at offset.
+// Ranges of encoded responses apply to the encoded bytes, which cannot resume a decoded download.
+func resumes(res *http.Response, offset int64) bool {
+	if res.StatusCode != http.StatusPartialContent || res.Header.Get("Content-Encoding") != "" {
+		return false
+	}
+	return strings.HasPrefix(res.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset))
+}
+
+// decodedBody returns the body of res, decompressed if it has a deflate Content-Encoding,
+// which the transport does not decode, unlike the gzip it requests by itself.
+//
+// Per RFC 9110 §8.4.1.2, deflate designates the zlib format, but some servers send raw
+// deflate data, so the zlib header is checked before choosing the decompressor.
+func decodedBody(res *http.Response) io.Reader {
+	if !strings.EqualFold(res.Header.Get("Content-Encoding"), "deflate") {
+		return res.Body
+	}
+	br := bufio.NewReader(res.Body)
+	if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+		if zr, err := zlib.NewReader(br); err == nil {
+			return zr
+		}
+	}
+	return flate.NewReader(br)
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"compress/flate"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+func TestFetcher_downloadAny_resume(t *testing.T) {
+	body := testRegistry("2023-01-01")
+	const offset = 20
+	// serveContent supports Range and If-Range, ignore does not support ranges.
+	serveContent := func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("ETag", `"1"`)
+		http.ServeContent(w, req, "registry.txt", time.Time{}, strings.NewReader(body))
+	}
+	ignore := func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("ETag", `"1"`)
+		w.Write([]byte(body))
+	}
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		part      string
+		partETag  string // Empty for a partial download without validators.
+		wantRange string
+		wantErr   bool
+	}{
+		{"range supported", serveContent, body[:offset], `"1"`, "bytes=20-", false},
+		{"range not supported", ignore, body[:offset], `"1"`, "bytes=20-", false},
+		{"changed since", serveContent, "garbled partial download", `"0"`, "bytes=24-", false},
+		{"no validators", serveContent, "garbled partial download", "", "", false},
+		{"garbled resume", serveContent, strings.Replace(body[:offset], "01-", "13-", 1), `"1"`, "bytes=20-", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gotRange string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				gotRange = req.Header.Get("Range")
+				test.handler(w, req)
+			}))
+			defer srv.Close()
+			f, cache := newTestFetcher(t)
+			part := f.StatePath + partialSuffix
+			if err := os.WriteFile(part, []byte(test.part), 0666); err != nil {
+				t.Fatal(err)
+			}
+			if test.partETag != "" {
+				if err := writeMeta(part+metaSuffix, cacheMeta{ETag: test.partETag}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := f.downloadAny(context.Background(), []string{srv.URL}, cache, false)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("downloadAny() = %v, want error %t", err, test.wantErr)
+			}
+			if gotRange != test.wantRange {
+				t.Errorf("request had Range %q, want %q", gotRange, test.wantRange)
+			}
+			// Complete or invalid, the partial download is gone.
+			if _, err := os.Stat(part); !os.IsNotExist(err) {
+				t.Errorf("partial download still exists: %v", err)
+			}
+			got, err := os.ReadFile(cache.Path)
+			if test.wantErr {
+				if !os.IsNotExist(err) {
+					t.Errorf("cache holds %q, %v, want no cache", got, err)
+				}
+				return
+			}
+			if err != nil || string(got) != body {
+				t.Errorf("cache holds %q, %v, want %q", got, err, body)
+			}
+		})
+	}
+}
+
+func TestFetcher_source(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(testRegistry("2023-01-01")))
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name     string
+		download bool
+	}{
+		{"downloaded", true},
+		{"copied to the cache", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, cache := newTestFetcher(t)
+			want := cache.Path
+			if test.download {
+				if err := f.downloadAny(context.Background(), []string{srv.URL}, cache, false); err != nil {
+					t.Fatalf("downloadAny() failed: %v", err)
+				}
+				want = srv.URL
+			} else if err := cache.Store(strings.NewReader(testRegistry("2023-01-01"))); err != nil {
+				t.Fatal(err)
+			}
+			r, err := f.parseCache(cache, registry.Options{})
+			if err != nil || r.Source != want {
+				t.Errorf("parseCache() = %+v, %v, want source %q", r, err, want)
+			}
+		})
+	}
+}
+
+func TestFetcher_downloadAny_failover(t *testing.T) {
+	var requested []string
+	handler := func(name string, status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			requested = append(requested, name)
+			if status != http.StatusOK {
+				http.Error(w, "unavailable", status)
+				return
+			}
+			w.Write([]byte(testRegistry("2023-08-02")))
+		}
+	}
+	failing := httptest.NewServer(handler("failing", http.StatusInternalServerError))
+	defer failing.Close()
+	mirror := httptest.NewServer(handler("mirror", http.StatusOK))
+	defer mirror.Close()
+	unused := httptest.NewServer(handler("unused", http.StatusOK))
+	defer unused.Close()
+
+	tests := []struct {
+		name    string
+		urls    []string
+		want    []string
+		wantErr bool
+	}{
+		{"first fails", []string{failing.URL, mirror.URL, unused.URL}, []string{"failing", "mirror"}, false},
+		{"first succeeds", []string{mirror.URL, failing.URL}, []string{"mirror"}, false},
+		{"all fail", []string{failing.URL, failing.URL}, []string{"failing", "failing"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requested = nil
+			f, cache := newTestFetcher(t)
+			err := f.downloadAny(context.Background(), test.urls, cache, false)
+			if (err != nil) != test.wantErr || !slices.Equal(requested, test.want) {
+				t.Fatalf("downloadAny() = %v, requesting %q, want error %t, requesting %q", err, requested, test.wantErr, test.want)
+			}
+			if test.wantErr {
+				if !strings.Contains(err.Error(), "500") {
+					t.Errorf("downloadAny() = %v, want the HTTP status", err)
+				}
+				return
+			}
+			r, err := f.parseCache(cache, registry.Options{})
+			if err != nil || r.FileDate.String() != "2023-08-02" || r.Source != mirror.URL {
+				t.Errorf("parseCache() = %+v, %v, want the registry from %s", r, err, mirror.URL)
+			}
+		})
+	}
+}
+
+func TestWriteMeta_readMeta(t *testing.T) {
+	tests := []struct {
+		name string
+		meta cacheMeta
+	}{
+		{"all", cacheMeta{ETag: `"abc"`, LastModified: "Wed, 02 Aug 2023 10:00:00 GMT", URL: "https://example.com/registry"}},
+		{"weak ETag", cacheMeta{ETag: `W/"abc"`}},
+		{"empty", cacheMeta{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "registry.txt"+metaSuffix)
+			if err := writeMeta(path, test.meta); err != nil {
+				t.Fatalf("writeMeta() = %v", err)
+			}
+			if got, err := readMeta(path); err != nil || got != test.meta {
+				t.Errorf("readMeta() = %+v, %v, want %+v", got, err, test.meta)
+			}
+		})
+	}
+}
+
+func TestFetcher_downloadAny_conditional(t *testing.T) {
+	const lastModified = "Wed, 02 Aug 2023 10:00:00 GMT"
+	cached, current := testRegistry("2023-01-01"), testRegistry("2023-08-02")
+	tests := []struct {
+		name     string
+		prev     cacheMeta
+		want     string
+		wantMeta cacheMeta
+	}{
+		{"same ETag", cacheMeta{ETag: `"2"`, URL: "previous"}, cached, cacheMeta{ETag: `"2"`, URL: "previous"}},
+		{"same Last-Modified", cacheMeta{LastModified: lastModified, URL: "previous"}, cached, cacheMeta{LastModified: lastModified, URL: "previous"}},
+		{"changed", cacheMeta{ETag: `"1"`}, current, cacheMeta{ETag: `"2"`, LastModified: lastModified}},
+		{"no validators", cacheMeta{}, current, cacheMeta{ETag: `"2"`, LastModified: lastModified}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.Header.Get("If-None-Match") == `"2"` || req.Header.Get("If-Modified-Since") == lastModified {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", `"2"`)
+				w.Header().Set("Last-Modified", lastModified)
+				w.Write([]byte(current))
+			}))
+			defer srv.Close()
+			f, cache := newTestFetcher(t)
+			if err := os.WriteFile(cache.Path, []byte(cached), 0666); err != nil {
+				t.Fatal(err)
+			}
+			metaPath := f.StatePath + metaSuffix
+			if err := writeMeta(metaPath, test.prev); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.downloadAny(context.Background(), []string{srv.URL}, cache, true); err != nil {
+				t.Fatalf("downloadAny() = %v", err)
+			}
+			if got, _ := os.ReadFile(cache.Path); string(got) != test.want {
+				t.Errorf("cache holds %q, want %q", got, test.want)
+			}
+			if test.want == current {
+				test.wantMeta.URL = srv.URL // Only known once the server started.
+			}
+			if got, err := readMeta(metaPath); err != nil || got != test.wantMeta {
+				t.Errorf("readMeta() = %+v, %v, want %+v", got, err, test.wantMeta)
+			}
+		})
+	}
+}
+
+func TestFetcher_client(t *testing.T) {
+	custom := &http.Client{Timeout: time.Second}
+	tests := []struct {
+		name        string
+		client      *http.Client
+		wantTimeout time.Duration
+	}{
+		{"default", nil, DefaultTimeout},
+		{"custom", custom, time.Second},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Fetcher{Client: test.client}.client()
+			if got.Timeout != test.wantTimeout || test.client != nil && got != test.client {
+				t.Errorf("client() = %+v, want timeout %s", got, test.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestFetcher_downloadAny_hung(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	tests := []struct {
+		name   string
+		client *http.Client
+		cancel bool
+	}{
+		{"client timeout", &http.Client{Timeout: 50 * time.Millisecond}, false},
+		{"canceled context", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if test.cancel {
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+			f, cache := newTestFetcher(t)
+			f.Client = test.client
+			done := make(chan error, 1)
+			go func() { done <- f.downloadAny(ctx, []string{srv.URL}, cache, false) }()
+			select {
+			case err := <-done:
+				if err == nil {
+					t.Error("downloadAny() = nil, want an error")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("downloadAny() still blocked on the hung server")
+			}
+		})
+	}
+}
+
+func TestFetcher_downloadAny_deflate(t *testing.T) {
+	body := testRegistry("2023-08-02")
+	tests := []struct {
+		name     string
+		encoding string
+		encode   func(io.Writer) io.WriteCloser
+	}{
+		{"zlib", "deflate", func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }},
+		{"raw deflate", "Deflate", func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		}},
+		{"identity", "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if test.encode == nil {
+					io.WriteString(w, body)
+					return
+				}
+				w.Header().Set("Content-Encoding", test.encoding)
+				ew := test.encode(w)
+				io.WriteString(ew, body)
+				ew.Close()
+			}))
+			defer srv.Close()
+			f, cache := newTestFetcher(t)
+			if err := f.downloadAny(context.Background(), []string{srv.URL}, cache, false); err != nil {
+				t.Fatalf("downloadAny() = %v", err)
+			}
+			if got, err := os.ReadFile(cache.Path); err != nil || string(got) != body {
+				t.Errorf("cache holds %q, %v, want %q", got, err, body)
+			}
+		})
+	}
+}
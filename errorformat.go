@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+// Error formats for -error-format.
+const (
+	ErrorFormatText = "text"
+	ErrorFormatJSON = "json"
+)
+
+// errorFormat is the -error-format used by logErrors.
+var errorFormat = ErrorFormatText
+
+// errorReport is the JSON form of an error, with the registry.ParseError fields for parse errors.
+type errorReport struct {
+	Message string `json:"message"`
+	Block   *int   `json:"block,omitempty"`
+	Key     string `json:"key,omitempty"`
+	Value   string `json:"value,omitempty"`
+}
+
+// newErrorReports flattens err, joined like by errors.Join, into reports.
+func newErrorReports(err error) []errorReport {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var reports []errorReport
+		for _, err := range joined.Unwrap() {
+			reports = append(reports, newErrorReports(err)...)
+		}
+		return reports
+	}
+	report := errorReport{Message: err.Error()}
+	var pe *registry.ParseError
+	if errors.As(err, &pe) {
+		report.Block, report.Key, report.Value = &pe.Block, pe.Key, pe.Value
+	}
+	return []errorReport{report}
+}
+
+// logErrors logs err using format, a log.Printf format with a single %v verb for err.
+// With the json errorFormat, it writes a JSON array of errorReport on a single line
+// of the standard error instead, for CI jobs.
+func logErrors(format string, err error) {
+	if errorFormat != ErrorFormatJSON {
+		log.Printf(format, err)
+		return
+	}
+	b, jerr := json.Marshal(newErrorReports(err))
+	if jerr != nil {
+		log.Printf(format, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", b)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+func TestNewErrorReports(t *testing.T) {
+	const head = "File-Date: 2023-08-02\n%%\nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n%%\n"
+	tests := []struct {
+		name string
+		text string // Parsed for the error, unless empty.
+		err  error
+		want []map[string]any
+	}{
+		{"malformed block", head + "Type: language\nSubtag: fr\nAdded: 16/10/2005\n", nil, []map[string]any{
+			{"message": `block 2: key added failed parsing value "16/10/2005": parsing time "16/10/2005" as "2006-01-02": cannot parse "16/10/2005" as "2006"`,
+				"block": 2.0, "key": "added", "value": "16/10/2005"},
+		}},
+		{"two malformed blocks", head + "Type: dialect\n%%\nType: language\nScope: dialect\n", nil, []map[string]any{
+			{"message": `block 2: key type has unknown type "dialect"`, "block": 2.0, "key": "type", "value": "dialect"},
+			{"message": `block 3: key scope has unknown scope "dialect"`, "block": 3.0, "key": "scope", "value": "dialect"},
+		}},
+		{"other error", "", errors.New("no cached registry"), []map[string]any{{"message": "no cached registry"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.err
+			if test.text != "" {
+				if _, err = registry.ParseWith(strings.NewReader(test.text), registry.Options{}); err == nil {
+					t.Fatal("ParseWith() succeeded, want an error")
+				}
+			}
+			b, err := json.Marshal(newErrorReports(err))
+			if err != nil {
+				t.Fatalf("failed encoding reports: %v", err)
+			}
+			var got []map[string]any
+			if err = json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("failed decoding %s: %v", b, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("reports are %s, want %v", b, test.want)
+			}
+		})
+	}
+}
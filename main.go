@@ -0,0 +1,504 @@
+// Command iana_lang_registry_tools downloads the IANA language subtag registry, parses it
+// with the registry package, and serializes it in one of several formats.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+// DefaultCachePath is the default -cache file, keeping the downloaded registry between runs.
+const DefaultCachePath = "registry.txt"
+
+// urlList is a flag.Value collecting the values of a repeated flag.
+type urlList []string
+
+// Set implements flag.Value.
+func (l *urlList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// String implements flag.Value.
+func (l *urlList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// dateRange is a flag.Value for a START,END range of dates, both included.
+type dateRange struct {
+	start, end registry.Date
+}
+
+// Set implements flag.Value.
+func (dr *dateRange) Set(s string) error {
+	start, end, ok := strings.Cut(s, ",")
+	if !ok {
+		return errors.New("expected START,END dates")
+	}
+	if err := dr.start.Set(start); err != nil {
+		return err
+	}
+	if err := dr.end.Set(end); err != nil {
+		return err
+	}
+	if time.Time(dr.end).Before(time.Time(dr.start)) {
+		return errors.New("END is before START")
+	}
+	return nil
+}
+
+// String implements flag.Value.
+func (dr *dateRange) String() string {
+	if dr.start.IsZero() {
+		return ""
+	}
+	return dr.start.String() + "," + dr.end.String()
+}
+
+// FormatPatch is the -format emitting the YAML registry.Patch from the -diff registry.
+const FormatPatch = "patch"
+
+// loadRegistry parses the registry from the cache, through openCache, recording its source.
+func loadRegistry(ctx context.Context, f Fetcher, cache registry.Cache, opts registry.Options) *registry.Registry {
+	rc := openCache(ctx, f, cache)
+	defer rc.Close()
+	r, err := registry.ParseWith(rc, opts)
+	if err != nil {
+		logErrors("Failed parsing registry: %v", err)
+		os.Exit(1)
+	}
+	r.Source = f.source(cache)
+	return r
+}
+
+// transformRegistry applies -expand-ranges and -overlay to r, then validates the result,
+// so that the problems they introduce, like duplicates created by expanding ranges, are reported.
+// With strict, failing the integrity checks is an error.
+func transformRegistry(r *registry.Registry, expand bool, overlay map[string]string, opts registry.Options, strict bool) error {
+	if expand {
+		if err := r.ExpandRanges(); err != nil {
+			return err
+		}
+	}
+	if overlay != nil {
+		for _, k := range r.ApplyOverlay(overlay) {
+			log.Printf("Skipping unknown subtag in overlay: %q", k)
+		}
+	}
+	if err := r.Validate(opts); err != nil {
+		logErrors("Registry validation found problems:\n%v", err)
+		if strict && r.ValidateIntegrity(opts) != nil {
+			return errors.New("invalid registry, with -strict")
+		}
+	}
+	return nil
+}
+
+// openCache opens the cached registry, after refreshing it with refreshCache.
+// Failures are fatal.
+func openCache(ctx context.Context, f Fetcher, cache registry.Cache) io.ReadCloser {
+	if err := refreshCache(ctx, f, cache); err != nil {
+		log.Fatalf("Failed loading registry: %v", err)
+	}
+	rc, ok, err := cache.Load()
+	if err == nil && !ok {
+		err = errors.New("missing after download")
+	}
+	if err != nil {
+		log.Fatalf("Failed opening cached registry: %v", err)
+	}
+	return rc
+}
+
+// refreshCache downloads the registry to the cache from the first of f.URLs to succeed
+// if it is missing, invalid, or stale per f.MaxAge and f.Refresh.
+//
+// When refreshing a valid cache fails, the existing cache is kept, without an error.
+// With f.Offline, a valid cache is always kept, and its absence is an error.
+func refreshCache(ctx context.Context, f Fetcher, cache registry.Cache) error {
+	valid, fresh, err := f.cacheStatus(cache)
+	switch {
+	case fresh:
+	case f.Offline && valid:
+		log.Print("Offline: using the cached registry, even if stale")
+	case f.Offline:
+		if err == nil {
+			err = errors.New("no cached registry")
+		}
+		return fmt.Errorf("offline and no valid cached registry in %v: %w", cache, err)
+	case valid:
+		log.Print("Refreshing cached registry")
+		if err = f.downloadAny(ctx, f.sourceURLs(), cache, true); err != nil {
+			log.Printf("Failed refreshing registry, using the existing cache: %v", err)
+		}
+	default:
+		if err != nil {
+			log.Printf("Ignoring invalid cached registry, fetching a fresh one: %v", err)
+		}
+		if err = f.downloadAny(ctx, f.sourceURLs(), cache, false); err != nil {
+			return fmt.Errorf("no cache and failed downloading online version: %w", err)
+		}
+	}
+	return nil
+}
+
+// cacheStatus reports whether the cache holds a valid registry and, if so,
+// whether it is fresh enough to be used without downloading it again.
+// Like for Cache.Load, a missing registry is not an error.
+func (f Fetcher) cacheStatus(cache registry.Cache) (valid, fresh bool, err error) {
+	rc, ok, err := cache.Load()
+	if err != nil || !ok {
+		return false, false, err
+	}
+	defer rc.Close()
+	// Read the start of the registry once for both checks.
+	var head bytes.Buffer
+	if err = registry.CheckBlocks(io.TeeReader(rc, &head)); err != nil {
+		return false, false, err
+	}
+	fd, err := registry.ReadFileDate(&head)
+	if err != nil {
+		return false, false, err
+	}
+	if f.Refresh {
+		return true, false, nil
+	}
+	if age := time.Since(time.Time(fd)); f.MaxAge > 0 && age > f.MaxAge {
+		log.Printf("Cached registry is stale: File-Date %s is older than %s", fd, f.MaxAge)
+		return true, false, nil
+	}
+	return true, true, nil
+}
+
+// useColor tells whether to color the output, per the -color mode: with auto,
+// only when writing to a terminal instead of files.
+func useColor(mode string, toFiles bool) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	case "auto":
+		return !toFiles && term.IsTerminal(int(os.Stdout.Fd()))
+	default:
+		log.Fatalf("Invalid -color %q: use auto, always, or never", mode)
+		return false
+	}
+}
+
+// readLines returns the non-blank lines of a file, trimmed.
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+// readRegistry parses the registry in a file, recording its path as its source.
+func readRegistry(path string) (*registry.Registry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := registry.Parse(f)
+	if err != nil {
+		return nil, err
+	}
+	r.Source = path
+	return r, nil
+}
+
+// readOverlay reads a YAML file mapping subtags to descriptions.
+func readOverlay(path string) (map[string]string, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var overlay map[string]string
+	if err = yaml.Unmarshal(bs, &overlay); err != nil {
+		return nil, err
+	}
+	return overlay, nil
+}
+
+// streamConflicts lists the flags -stream cannot honor, since they need the whole registry,
+// like validations or entry filters, or another output than the yaml format.
+var streamConflicts = []string{"deprecated-between", "deprecation-csv", "diff", "expand-ranges",
+	"formats", "min-added", "new-in-release", "order-file", "out-dir", "overlay", "search",
+	"stats", "stats-pct", "strict", "type", "validate-schema", "watch"}
+
+// streamConflict returns the name of the first flag set in fs which -stream cannot honor, or "".
+func streamConflict(fs *flag.FlagSet) string {
+	var name string
+	fs.Visit(func(f *flag.Flag) {
+		if name == "" && slices.Contains(streamConflicts, f.Name) {
+			name = f.Name
+		}
+	})
+	return name
+}
+
+func main() {
+	format := flag.String("format", registry.FormatYAML, "output format: yaml, json, text, grep, bytype, gomap, env, csv, script-langs, registry, sqlite, or patch")
+	output := flag.String("o", "", "write the output to this file instead of the standard output, {date} being replaced by the File-Date")
+	formats := flag.String("formats", "", "comma-separated formats, each written to a registry.FORMAT file in -out-dir")
+	outDir := flag.String("out-dir", "", "directory receiving the files written for -formats")
+	withHash := flag.Bool("with-hash", false, "write the SHA-256 of the output to a -o FILE.sha256 sidecar, or to stderr")
+	descriptionJoin := flag.String("description-join", registry.DefaultDescriptionJoin, "separator between multiple descriptions in the text and csv formats")
+	color := flag.String("color", "auto", "color the text and grep formats: auto, on terminals only, always, or never")
+	envelope := flag.Bool("envelope", false, "with -format json, wrap entries in an object with source, fileDate, and count")
+	faithful := flag.Bool("faithful", false, "keep the line breaks of values continued on multiple lines, like comments")
+	lenient := flag.Bool("lenient", false, "do not report missing required fields, for trimmed registries")
+	strict := flag.Bool("strict", false, "do not emit registries failing integrity checks: missing required fields, duplicate subtags or tags")
+	stats := flag.Bool("stats", false, "emit entry counts by type and scope instead of the registry")
+	statsPct := flag.Bool("stats-pct", false, "like -stats, with counts as percentages of all entries")
+	stream := flag.Bool("stream", false, "emit the yaml format entry by entry while parsing, to bound memory use")
+	overlayFile := flag.String("overlay", "", "YAML file mapping subtags to descriptions replacing the registry ones")
+	orderFile := flag.String("order-file", "", "emit the subtags listed one per line in this file first, in that order")
+	compactDates := flag.Bool("compact-dates", false, "with the yaml, json, and bytype formats, emit dates as integer days since the Unix epoch")
+	deprecationCSV := flag.Bool("deprecation-csv", false, "emit the old,new,date_deprecated CSV of deprecated entries instead of the registry")
+	var minAdded registry.Date
+	flag.Var(&minAdded, "min-added", "only emit entries added on or after this YYYY-MM-DD date")
+	var deprecatedBetween dateRange
+	flag.Var(&deprecatedBetween, "deprecated-between", "only emit entries deprecated within this START,END range of YYYY-MM-DD dates, both included")
+	newInRelease := flag.Bool("new-in-release", false, "only emit entries added on the registry File-Date")
+	expandRanges := flag.Bool("expand-ranges", false, "emit one entry per subtag for range entries like qaa..qtz")
+	search := flag.String("search", "", "only emit entries with this text in a description, compared case-insensitively")
+	typ := flag.String("type", "", "only emit entries of this type, like language or region")
+	schemaFile := flag.String("validate-schema", "", "also validate each entry, as JSON, against the JSON Schema in this file")
+	diffFile := flag.String("diff", "", "with -format patch, emit the changes from the older registry in this file")
+	maxAge := flag.Duration("max-age", 0, "download the registry again when the cached one has an older File-Date; 0 never does")
+	maxEntries := flag.Int("max-entries", 0, "fail parsing registries with more entries than this; 0 means no limit")
+	refresh := flag.Bool("refresh", false, "download the registry again even if the cached one is fresh")
+	timeout := flag.Duration("timeout", DefaultTimeout, "abort registry downloads taking longer than this; 0 never does")
+	cachePath := flag.String("cache", DefaultCachePath, "file keeping the downloaded registry between runs")
+	offline := flag.Bool("offline", false, "never download the registry, failing if the -cache file is missing or invalid")
+	var urls urlList
+	flag.Var(&urls, "url", "download the registry from this URL, repeated for mirrors tried in order (default "+registry.Url+")")
+	checksumURL := flag.String("checksum-url", "", "verify downloads against the SHA-256 checksum, in the sha256sum format, at this URL")
+	watch := flag.Duration("watch", 0, "re-fetch the registry at this interval and emit it again when it changes")
+	flag.StringVar(&errorFormat, "error-format", ErrorFormatText, "format of parse and validation errors: text, or json for one JSON array per line")
+	flag.Parse()
+	if errorFormat != ErrorFormatText && errorFormat != ErrorFormatJSON {
+		log.Fatalf("Invalid -error-format %q: use %s or %s", errorFormat, ErrorFormatText, ErrorFormatJSON)
+	}
+	switch flag.Arg(0) {
+	case "":
+	case CommandDiff:
+		if err := runDiff(os.Stdout, flag.Args()[1:]); err != nil {
+			log.Fatalf("Failed diffing registries: %v", err)
+		}
+		return
+	default:
+		log.Fatalf("Unknown command %q: use %s, or no command to emit the registry", flag.Arg(0), CommandDiff)
+	}
+	if *typ != "" && !registry.Type(*typ).IsValid() {
+		log.Fatalf("Invalid -type %q", *typ)
+	}
+	if *stream {
+		if *format != registry.FormatYAML {
+			log.Fatalf("-stream only supports the %s format", registry.FormatYAML)
+		}
+		if name := streamConflict(flag.CommandLine); name != "" {
+			log.Fatalf("-stream cannot be used with -%s", name)
+		}
+	}
+
+	opts := registry.Options{
+		Color:           useColor(*color, *output != "" || *outDir != ""),
+		CompactDates:    *compactDates,
+		DescriptionJoin: *descriptionJoin,
+		Envelope:        *envelope,
+		Faithful:        *faithful,
+		Lenient:         *lenient,
+		MaxEntries:      *maxEntries,
+	}
+	cache := registry.FileCache{Path: *cachePath}
+	fetcher := Fetcher{
+		Client:      &http.Client{Timeout: *timeout},
+		StatePath:   *cachePath,
+		URLs:        urls,
+		MaxAge:      *maxAge,
+		Refresh:     *refresh,
+		Offline:     *offline,
+		ChecksumURL: *checksumURL,
+	}
+	// Interrupting cancels downloads, and ends the -watch loop.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if strings.Contains(*output, dateVar) && *watch > 0 {
+		log.Fatalf("-o %s cannot be used with -watch", dateVar)
+	}
+	// The File-Date of the emitted registry, expanding the -o file name.
+	var fileDate registry.Date
+	var out io.Writer = os.Stdout
+	var of *outputFile
+	if *output != "" && *format != FormatSQLite {
+		of = &outputFile{name: *output}
+		defer of.Close()
+		out = of
+	}
+	hasher := sha256.New()
+	if *withHash && *formats == "" {
+		out = io.MultiWriter(out, hasher)
+		defer func() {
+			sum := hasher.Sum(nil)
+			if *format == FormatSQLite {
+				var err error
+				if sum, err = fileSHA256(expandOutput(*output, fileDate)); err != nil {
+					log.Fatalf("Failed hashing output: %v", err)
+				}
+			}
+			if err := writeChecksum(sum, expandOutput(*output, fileDate)); err != nil {
+				log.Fatalf("Failed writing output hash: %v", err)
+			}
+		}()
+	}
+	var (
+		encode registry.Encoder
+		err    error
+	)
+	if *formats != "" || *outDir != "" {
+		if *formats == "" || *outDir == "" || *output != "" {
+			log.Fatalf("-formats and -out-dir go together, without -o")
+		}
+		if encode, err = newDirEncoder(*outDir, strings.Split(*formats, ","), opts, *withHash); err != nil {
+			log.Fatalf("Invalid -formats: %v", err)
+		}
+	} else if *format == FormatSQLite {
+		if *output == "" {
+			log.Fatalf("-format %s needs an -o database file", FormatSQLite)
+		}
+		encode = func(r registry.Registry) error { return writeSQLite(expandOutput(*output, r.FileDate), r) }
+	} else if *format == FormatPatch {
+		if *diffFile == "" {
+			log.Fatalf("-format %s needs a -diff registry file", FormatPatch)
+		}
+		from, err := readRegistry(*diffFile)
+		if err != nil {
+			log.Fatalf("Failed reading -diff: %v", err)
+		}
+		e := yaml.NewEncoder(out)
+		encode = func(r registry.Registry) error { return e.Encode(registry.Diff(*from, r)) }
+	} else if *diffFile != "" {
+		log.Fatalf("-diff needs -format %s", FormatPatch)
+	} else if encode, err = registry.NewEncoder(out, *format, opts); err != nil {
+		log.Fatalf("Invalid -format: %v", err)
+	}
+	var overlay map[string]string
+	if *overlayFile != "" {
+		if overlay, err = readOverlay(*overlayFile); err != nil {
+			log.Fatalf("Failed reading -overlay: %v", err)
+		}
+	}
+	var order []string
+	if *orderFile != "" {
+		if order, err = readLines(*orderFile); err != nil {
+			log.Fatalf("Failed reading -order-file: %v", err)
+		}
+	}
+	emit := func(r registry.Registry) error {
+		if fileDate = r.FileDate; of != nil {
+			of.FileDate = r.FileDate
+		}
+		if err := transformRegistry(&r, *expandRanges, overlay, opts, *strict); err != nil {
+			return err
+		}
+		if *schemaFile != "" {
+			if err := r.ValidateSchema(*schemaFile); err != nil {
+				logErrors("Registry schema validation found problems:\n%v", err)
+			}
+		}
+		if !minAdded.IsZero() {
+			r.Entries = r.AddedSince(minAdded)
+		}
+		if *newInRelease {
+			r.Entries = r.AddedInRelease()
+		}
+		if !deprecatedBetween.start.IsZero() {
+			r.Entries = r.DeprecatedBetween(deprecatedBetween.start, deprecatedBetween.end)
+		}
+		if *typ != "" {
+			r.Entries = r.ByType(registry.Type(*typ))
+		}
+		if *search != "" {
+			r.Entries = r.SearchDescription(*search)
+		}
+		if order != nil {
+			var unknown []string
+			r.Entries, unknown = r.Reordered(order)
+			for _, k := range unknown {
+				log.Printf("Skipping unknown subtag in order file: %q", k)
+			}
+		}
+		if *deprecationCSV {
+			return registry.WriteDeprecationCSV(out, r)
+		}
+		if *stats || *statsPct {
+			return r.Stats().Write(out, *statsPct)
+		}
+		return encode(r)
+	}
+	if *stream {
+		rc := openCache(ctx, fetcher, cache)
+		defer rc.Close()
+		// Read the File-Date for the -o file name, then stream the registry from its start again.
+		var head bytes.Buffer
+		if fd, err := registry.ReadFileDate(io.TeeReader(rc, &head)); err == nil {
+			if fileDate = fd; of != nil {
+				of.FileDate = fd
+			}
+		}
+		if err := registry.StreamYAML(out, io.MultiReader(&head, rc), opts); err != nil {
+			logErrors("Failed streaming registry: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *watch > 0 {
+		if fetcher.Offline {
+			log.Fatalf("-watch cannot be used with -offline")
+		}
+		fetcher.watchRegistry(ctx, cache, opts, *watch, func(r registry.Registry) {
+			if err := emit(r); err != nil {
+				log.Printf("Failed encoding registry: %v", err)
+			}
+		})
+		return
+	}
+
+	r := loadRegistry(ctx, fetcher, cache, opts)
+	log.Printf("%d entries in registry", len(r.Entries))
+
+	if err := emit(*r); err != nil {
+		log.Fatalf("Failed encoding registry: %v", err)
+	}
+}
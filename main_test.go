@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+// writeTemp writes content to a file named name in a temporary directory, returning its path.
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+		t.Fatalf("failed writing %s: %v", name, err)
+	}
+	return path
+}
+
+func TestReadLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"lines", "fr\nen\nde\n", []string{"fr", "en", "de"}},
+		{"blank lines and padding", "\n  fr \n\n\ten\nde", []string{"fr", "en", "de"}},
+		{"empty", "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := readLines(writeTemp(t, "order.txt", test.content))
+			if err != nil || !slices.Equal(got, test.want) {
+				t.Errorf("readLines() = %q, %v, want %q", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestReadOverlay(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"overlay", "qaa: Team language\nQaab: Team script\n", map[string]string{"qaa": "Team language", "Qaab": "Team script"}, false},
+		{"empty", "", nil, false},
+		{"not a map", "- qaa\n", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := readOverlay(writeTemp(t, "overlay.yaml", test.content))
+			if (err != nil) != test.wantErr || !maps.Equal(got, test.want) {
+				t.Errorf("readOverlay() = %q, %v, want %q, error %t", got, err, test.want, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestStreamConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"alone", []string{"-stream"}, ""},
+		{"honored flags", []string{"-stream", "-o", "out.yaml", "-compact-dates", "-max-entries", "10"}, ""},
+		{"filter", []string{"-stream", "-type", "language"}, "type"},
+		{"first in name order", []string{"-strict", "-stream", "-search", "chinese"}, "search"},
+		{"validation", []string{"-stream", "-strict"}, "strict"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.Bool("stream", false, "")
+			fs.Bool("compact-dates", false, "")
+			fs.Bool("strict", false, "")
+			fs.Int("max-entries", 0, "")
+			fs.String("o", "", "")
+			fs.String("search", "", "")
+			fs.String("type", "", "")
+			if err := fs.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			if got := streamConflict(fs); got != test.want {
+				t.Errorf("streamConflict(%q) = %q, want %q", test.args, got, test.want)
+			}
+		})
+	}
+}
+
+func TestTransformRegistry(t *testing.T) {
+	const text = "File-Date: 2023-08-02\n%%\n" +
+		"Type: language\nSubtag: qaa..qtz\nDescription: Private use\nAdded: 2005-10-16\nScope: private-use\n%%\n" +
+		"Type: language\nSubtag: qab\nDescription: Local language\nAdded: 2023-08-02\n"
+	tests := []struct {
+		name    string
+		expand  bool
+		strict  bool
+		wantErr bool
+	}{
+		{"strict", false, true, false},
+		{"expansion creating a duplicate", true, true, true},
+		{"expansion without strict", true, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := registry.Parse(strings.NewReader(text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := transformRegistry(r, test.expand, nil, registry.Options{}, test.strict); (err != nil) != test.wantErr {
+				t.Errorf("transformRegistry() = %v, want an error: %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRefreshCache_invalid(t *testing.T) {
+	fresh := testRegistry("2023-08-02")
+	tests := []struct {
+		name      string
+		cached    string
+		wantFetch bool
+	}{
+		{"valid", testRegistry("2023-01-01"), false},
+		{"truncated after the File-Date", "File-Date: 2023-01-01\n%%\n", true},
+		{"truncated in the File-Date", "File-Da", true},
+		{"garbled", "\x00\x01garbage\n%%\nType: language\n", true},
+		{"empty", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var fetches int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				fetches++
+				io.WriteString(w, fresh)
+			}))
+			defer srv.Close()
+			f, cache := newTestFetcher(t)
+			f.URLs = []string{srv.URL}
+			if err := os.WriteFile(cache.Path, []byte(test.cached), 0666); err != nil {
+				t.Fatal(err)
+			}
+			if err := refreshCache(context.Background(), f, cache); err != nil {
+				t.Fatalf("refreshCache() = %v", err)
+			}
+			want := test.cached
+			if test.wantFetch {
+				want = fresh
+			}
+			if got, _ := os.ReadFile(cache.Path); fetches != btoi(test.wantFetch) || string(got) != want {
+				t.Errorf("after %d fetches, cache holds %q, want %q", fetches, got, want)
+			}
+		})
+	}
+}
+
+func TestRefreshCache_staleness(t *testing.T) {
+	const fresh = "2023-08-02"
+	old := time.Now().AddDate(0, 0, -10).Format(time.DateOnly)
+	recent := time.Now().AddDate(0, 0, -1).Format(time.DateOnly)
+	tests := []struct {
+		name    string
+		cached  string
+		maxAge  time.Duration
+		refresh bool
+		failing bool // The server fails the download.
+		want    string
+	}{
+		{"no max age", old, 0, false, false, old},
+		{"younger than max age", recent, 72 * time.Hour, false, false, recent},
+		{"older than max age", old, 72 * time.Hour, false, false, fresh},
+		{"refresh", recent, 0, true, false, fresh},
+		{"failed refresh keeps the cache", old, 72 * time.Hour, false, true, old},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if test.failing {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				io.WriteString(w, testRegistry(fresh))
+			}))
+			defer srv.Close()
+			f, cache := newTestFetcher(t)
+			if err := os.WriteFile(cache.Path, []byte(testRegistry(test.cached)), 0666); err != nil {
+				t.Fatal(err)
+			}
+			f.URLs, f.MaxAge, f.Refresh = []string{srv.URL}, test.maxAge, test.refresh
+			if err := refreshCache(context.Background(), f, cache); err != nil {
+				t.Fatalf("refreshCache() = %v", err)
+			}
+			if got, _ := os.ReadFile(cache.Path); string(got) != testRegistry(test.want) {
+				t.Errorf("cache holds %q, want File-Date %s", got, test.want)
+			}
+		})
+	}
+}
+
+// memCache is a registry.Cache keeping the registry in memory.
+type memCache struct {
+	data   []byte
+	stored bool
+}
+
+func (c *memCache) Load() (io.ReadCloser, bool, error) {
+	if !c.stored {
+		return nil, false, nil
+	}
+	return io.NopCloser(bytes.NewReader(c.data)), true, nil
+}
+
+func (c *memCache) Store(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	c.data, c.stored = data, true
+	return nil
+}
+
+func TestLoadRegistry_memCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		cache     *memCache
+		wantFetch bool
+		want      string
+	}{
+		{"empty", &memCache{}, true, "2023-08-02"},
+		{"cached", &memCache{data: []byte(testRegistry("2023-01-01")), stored: true}, false, "2023-01-01"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var fetches int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				fetches++
+				io.WriteString(w, testRegistry("2023-08-02"))
+			}))
+			defer srv.Close()
+			f, _ := newTestFetcher(t)
+			f.URLs = []string{srv.URL}
+			r := loadRegistry(context.Background(), f, test.cache, registry.Options{})
+			if fetches != btoi(test.wantFetch) || r.FileDate.String() != test.want {
+				t.Errorf("after %d fetches, loadRegistry() has File-Date %s, want %s", fetches, r.FileDate, test.want)
+			}
+			if string(test.cache.data) != testRegistry(test.want) {
+				t.Errorf("cache holds %q, want File-Date %s", test.cache.data, test.want)
+			}
+		})
+	}
+}
+
+func TestURLList(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"default", nil, []string{registry.Url}},
+		{"one", []string{"-url", "http://mirror.example/registry"}, []string{"http://mirror.example/registry"}},
+		{"mirrors in order", []string{"-url", "http://a.example", "-url", "http://b.example"}, []string{"http://a.example", "http://b.example"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			var urls urlList
+			fs.Var(&urls, "url", "")
+			if err := fs.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			if got := (Fetcher{URLs: urls}).sourceURLs(); !slices.Equal(got, test.want) {
+				t.Errorf("sourceURLs() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRefreshCache_configured(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache string // Relative to a temporary directory.
+	}{
+		{"default name", DefaultCachePath},
+		{"custom path", filepath.Join("pinned", "snapshot.txt")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var fetches int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				fetches++
+				io.WriteString(w, testRegistry("2023-08-02"))
+			}))
+			defer srv.Close()
+			path := filepath.Join(t.TempDir(), test.cache)
+			if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+				t.Fatal(err)
+			}
+			f, cache := Fetcher{StatePath: path, URLs: []string{srv.URL}}, registry.FileCache{Path: path}
+			if err := refreshCache(context.Background(), f, cache); err != nil {
+				t.Fatalf("refreshCache() = %v", err)
+			}
+			if got, err := os.ReadFile(path); fetches != 1 || err != nil || string(got) != testRegistry("2023-08-02") {
+				t.Errorf("after %d fetches from -url, -cache holds %q, %v", fetches, got, err)
+			}
+		})
+	}
+}
+
+func TestRefreshCache_offline(t *testing.T) {
+	old := testRegistry("2000-01-01")
+	tests := []struct {
+		name    string
+		cached  *string // Nil for no cache.
+		wantErr string
+	}{
+		{"stale cache", &old, ""},
+		{"no cache", nil, "offline and no valid cached registry in "},
+		{"invalid cache", new(string), "offline and no valid cached registry in "},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var fetches int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				fetches++
+				io.WriteString(w, testRegistry("2023-08-02"))
+			}))
+			defer srv.Close()
+			f, cache := newTestFetcher(t)
+			f.URLs, f.MaxAge, f.Refresh, f.Offline = []string{srv.URL}, time.Hour, true, true
+			if test.cached != nil {
+				if err := os.WriteFile(cache.Path, []byte(*test.cached), 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := refreshCache(context.Background(), f, cache)
+			if fetches != 0 {
+				t.Errorf("refreshCache() made %d requests, want none", fetches)
+			}
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("refreshCache() = %v, want nil", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr+cache.Path)):
+				t.Errorf("refreshCache() = %v, want %q naming the cache", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	tests := []struct {
+		mode    string
+		toFiles bool
+		want    bool
+	}{
+		{"auto", false, false}, // The test output is not a terminal.
+		{"auto", true, false},
+		{"always", true, true},
+		{"never", false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.mode+"/"+strconv.FormatBool(test.toFiles), func(t *testing.T) {
+			if got := useColor(test.mode, test.toFiles); got != test.want {
+				t.Errorf("useColor(%q, %t) = %t, want %t", test.mode, test.toFiles, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDateRange_Set(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"2008-11-22,2009-07-29", "2008-11-22,2009-07-29", ""},
+		{"2009-07-29,2009-07-29", "2009-07-29,2009-07-29", ""},
+		{"2009-07-29,2008-11-22", "", "END is before START"},
+		{"2009-07-29", "", "expected START,END dates"},
+		{"2009-07-29,2009-13-01", "", "month out of range"},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			var dr dateRange
+			err := dr.Set(test.value)
+			switch {
+			case test.wantErr == "" && (err != nil || dr.String() != test.want):
+				t.Errorf("Set(%q) = %v, giving %q, want %q", test.value, err, dr.String(), test.want)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("Set(%q) = %v, want error %q", test.value, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+// newDirEncoder builds an encoder writing the registry in each of the formats to a
+// "registry.FORMAT" file in dir, like "registry.json", parsing the registry only once.
+// With withHash, each file also gets a checksum sidecar, as for -o.
+func newDirEncoder(dir string, formats []string, opts registry.Options, withHash bool) (registry.Encoder, error) {
+	for _, format := range formats {
+		if format == FormatSQLite {
+			continue
+		}
+		// Check the formats before writing anything.
+		if _, err := registry.NewEncoder(io.Discard, format, opts); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return func(r registry.Registry) error {
+		for _, format := range formats {
+			path := filepath.Join(dir, "registry."+format)
+			if err := writeFormat(path, format, r, opts); err != nil {
+				return fmt.Errorf("failed writing %s: %w", path, err)
+			}
+			if !withHash {
+				continue
+			}
+			sum, err := fileSHA256(path)
+			if err == nil {
+				err = writeChecksum(sum, path)
+			}
+			if err != nil {
+				return fmt.Errorf("failed hashing %s: %w", path, err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// writeFormat writes the registry in one format to the file at path.
+func writeFormat(path, format string, r registry.Registry, opts registry.Options) error {
+	if format == FormatSQLite {
+		return writeSQLite(path, r)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	encode, err := registry.NewEncoder(f, format, opts)
+	if err != nil {
+		return err
+	}
+	if err = encode(r); err != nil {
+		return err
+	}
+	return f.Close()
+}
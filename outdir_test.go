@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+func TestNewDirEncoder(t *testing.T) {
+	r, err := registry.Parse(strings.NewReader(testRegistry("2023-08-02")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "out")
+	encode, err := newDirEncoder(dir, []string{registry.FormatYAML, registry.FormatJSON}, registry.Options{}, true)
+	if err != nil {
+		t.Fatalf("newDirEncoder() failed: %v", err)
+	}
+	if err = encode(*r); err != nil {
+		t.Fatalf("encoding failed: %v", err)
+	}
+	tests := []struct {
+		file   string
+		decode func([]byte, any) error
+	}{
+		{"registry.yaml", yaml.Unmarshal},
+		{"registry.json", json.Unmarshal},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			bs, err := os.ReadFile(filepath.Join(dir, test.file))
+			if err != nil {
+				t.Fatalf("missing output: %v", err)
+			}
+			var got registry.Registry
+			if err = test.decode(bs, &got); err != nil {
+				t.Fatalf("invalid output: %v", err)
+			}
+			if got.FileDate.String() != "2023-08-02" || len(got.Entries) != 1 || got.Entries[0].Subtag != "de" {
+				t.Errorf("decoded %+v, want the registry", got)
+			}
+			if _, err = os.Stat(filepath.Join(dir, test.file+checksumSuffix)); err != nil {
+				t.Errorf("missing checksum sidecar: %v", err)
+			}
+		})
+	}
+
+	// Invalid formats are reported before writing anything.
+	dir = filepath.Join(t.TempDir(), "invalid")
+	if _, err = newDirEncoder(dir, []string{registry.FormatYAML, "xml"}, registry.Options{}, false); err == nil {
+		t.Errorf("newDirEncoder() with an unknown format succeeded")
+	}
+	if _, err = os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("newDirEncoder() with an unknown format created %s: %v", dir, err)
+	}
+}
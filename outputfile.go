@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+// dateVar is replaced by the File-Date of the registry in -o file names, like "registry-{date}.yaml".
+const dateVar = "{date}"
+
+// expandOutput returns the -o file name for a registry with the given File-Date.
+func expandOutput(name string, fileDate registry.Date) string {
+	return strings.ReplaceAll(name, dateVar, fileDate.String())
+}
+
+// outputFile is the -o file, only created on the first write, once the FileDate
+// expanding its name is known.
+type outputFile struct {
+	name     string
+	FileDate registry.Date
+	f        *os.File
+}
+
+// Write implements io.Writer.
+func (o *outputFile) Write(p []byte) (int, error) {
+	if o.f == nil {
+		f, err := os.Create(expandOutput(o.name, o.FileDate))
+		if err != nil {
+			return 0, fmt.Errorf("failed creating output file: %w", err)
+		}
+		o.f = f
+	}
+	return o.f.Write(p)
+}
+
+// Close closes the file, if it was created.
+func (o *outputFile) Close() error {
+	if o.f == nil {
+		return nil
+	}
+	return o.f.Close()
+}
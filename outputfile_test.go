@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+func TestExpandOutput(t *testing.T) {
+	r, err := registry.Parse(strings.NewReader(testRegistry("2023-08-02")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"registry-{date}.yaml", "registry-2023-08-02.yaml"},
+		{"{date}/{date}.json", "2023-08-02/2023-08-02.json"},
+		{"registry.yaml", "registry.yaml"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := expandOutput(test.name, r.FileDate); got != test.want {
+				t.Errorf("expandOutput(%q, %s) = %q, want %q", test.name, r.FileDate, got, test.want)
+			}
+		})
+	}
+}
+
+func TestOutputFile_Write(t *testing.T) {
+	dir := t.TempDir()
+	o := &outputFile{name: filepath.Join(dir, "registry-{date}.yaml")}
+	if err := o.Close(); err != nil {
+		t.Fatalf("Close() before writing = %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("Close() created %v, want no file before the first write", entries)
+	}
+	if err := o.FileDate.Set("2023-08-02"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Write([]byte("file-date: 2023-08-02\n")); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	if err := o.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "registry-2023-08-02.yaml")); err != nil || string(got) != "file-date: 2023-08-02\n" {
+		t.Errorf("output holds %q, %v, want the written bytes", got, err)
+	}
+}
@@ -0,0 +1,61 @@
+package registry
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Cache stores a copy of the registry text between runs, to avoid downloading it every time.
+type Cache interface {
+	// Load returns the cached registry, or false if there is none.
+	Load() (io.ReadCloser, bool, error)
+	// Store replaces the cached registry with the one read from r.
+	Store(r io.Reader) error
+}
+
+// FileCache is a Cache keeping the registry in the file at Path.
+type FileCache struct {
+	Path string
+}
+
+// String implements fmt.Stringer, describing the cache by its Path.
+func (c FileCache) String() string {
+	return c.Path
+}
+
+// Load implements Cache.
+func (c FileCache) Load() (io.ReadCloser, bool, error) {
+	f, err := os.Open(c.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return f, true, nil
+}
+
+// Store implements Cache, through a temporary file renamed to Path once complete,
+// so that a failed Store leaves the previous copy in place.
+func (c FileCache) Store(r io.Reader) error {
+	f, err := os.CreateTemp(filepath.Dir(c.Path), filepath.Base(c.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	// Like os.WriteFile with 0666 and the usual umask, unlike the private CreateTemp default.
+	if err = f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.Path)
+}
@@ -0,0 +1,319 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// tagParts holds the lower-cased subtags of a language tag, per RFC 5646 §2.1.
+type tagParts struct {
+	language string
+	extlangs []string
+	script   string
+	region   string
+	variants []string
+	rest     []string // Extensions and private use, from the first singleton on.
+}
+
+// isAlpha reports whether s is only made of ASCII letters.
+func isAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isDigits reports whether s is only made of ASCII digits.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isAlnum reports whether s is only made of ASCII letters and digits.
+func isAlnum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isAlpha(s[i:i+1]) && !isDigits(s[i:i+1]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isVariant reports whether s has the shape of a variant subtag:
+// 5 to 8 letters or digits, or 4 starting with a digit.
+func isVariant(s string) bool {
+	return isAlnum(s) && (len(s) >= 5 && len(s) <= 8 || len(s) == 4 && isDigits(s[:1]))
+}
+
+// splitTag splits a language tag into its subtags, based on their shape.
+func splitTag(tag string) (tagParts, error) {
+	var p tagParts
+	subtags := strings.Split(strings.ToLower(tag), "-")
+	s := subtags[0]
+	if s == "x" {
+		p.rest = subtags
+		return p, checkExtensions(p.rest, tag)
+	}
+	if !isAlpha(s) || len(s) < 2 || len(s) > 8 {
+		return p, fmt.Errorf("malformed language subtag %q in tag %q", s, tag)
+	}
+	p.language, subtags = s, subtags[1:]
+	for len(p.language) <= 3 && len(p.extlangs) < 3 && len(subtags) > 0 && len(subtags[0]) == 3 && isAlpha(subtags[0]) {
+		p.extlangs, subtags = append(p.extlangs, subtags[0]), subtags[1:]
+	}
+	if len(subtags) > 0 && len(subtags[0]) == 4 && isAlpha(subtags[0]) {
+		p.script, subtags = subtags[0], subtags[1:]
+	}
+	if len(subtags) > 0 && (len(subtags[0]) == 2 && isAlpha(subtags[0]) || len(subtags[0]) == 3 && isDigits(subtags[0])) {
+		p.region, subtags = subtags[0], subtags[1:]
+	}
+	for len(subtags) > 0 && isVariant(subtags[0]) {
+		p.variants, subtags = append(p.variants, subtags[0]), subtags[1:]
+	}
+	if len(subtags) > 0 {
+		p.rest = subtags
+		return p, checkExtensions(p.rest, tag)
+	}
+	return p, nil
+}
+
+// checkExtensions checks the extensions and private use subtags of a tag, from its first singleton on:
+// each singleton must be followed by at least one subtag, of 2 to 8 letters or digits for extensions,
+// and of 1 to 8 for the private use subtags following "x", per RFC 5646 §2.1.
+func checkExtensions(rest []string, tag string) error {
+	for len(rest) > 0 {
+		singleton := rest[0]
+		if len(singleton) != 1 || !isAlnum(singleton) {
+			return fmt.Errorf("malformed subtag %q in tag %q", singleton, tag)
+		}
+		if len(rest) == 1 {
+			return fmt.Errorf("singleton %q without a following subtag in tag %q", singleton, tag)
+		}
+		if singleton == "x" {
+			for _, s := range rest[1:] {
+				if len(s) > 8 || !isAlnum(s) {
+					return fmt.Errorf("malformed private use subtag %q in tag %q", s, tag)
+				}
+			}
+			return nil
+		}
+		n := 1
+		for n < len(rest) && len(rest[n]) >= 2 && len(rest[n]) <= 8 && isAlnum(rest[n]) {
+			n++
+		}
+		if n == 1 {
+			return fmt.Errorf("singleton %q without a following subtag in tag %q", singleton, tag)
+		}
+		rest = rest[n:]
+	}
+	return nil
+}
+
+// rangeContains reports whether a range subtag like "qaa..qtz" contains the lower-cased subtag s.
+func rangeContains(rangeSubtag, s string) bool {
+	lo, hi, ok := strings.Cut(strings.ToLower(rangeSubtag), "..")
+	return ok && len(lo) == len(s) && len(hi) == len(s) && lo <= s && s <= hi
+}
+
+// canonicalSubtag returns the subtag replacing a deprecated one, following the Preferred-Value
+// of each deprecated subtag until a current one, or the subtag itself if it is current.
+//
+// It fails if a subtag in the chain is not in the registry with the given type,
+// or if the chain loops.
+func (r Registry) canonicalSubtag(idx *subtagIndex, subtag string, typ Type) (string, error) {
+	var chain []string
+	for {
+		e, ok := idx.find(r.Entries, subtag, typ)
+		if !ok && len(chain) == 0 {
+			return "", fmt.Errorf("unknown %s subtag %q", typ, subtag)
+		}
+		if !ok {
+			return "", fmt.Errorf("%s subtag %q has the unknown Preferred-Value %q", typ, chain[len(chain)-1], subtag)
+		}
+		if e.Deprecated.IsZero() || e.PreferredValue == "" {
+			return subtag, nil
+		}
+		chain = append(chain, subtag)
+		subtag = strings.ToLower(e.PreferredValue)
+		if slices.Contains(chain, subtag) {
+			return "", fmt.Errorf("cycle of Preferred-Value for %s subtags %s", typ, strings.Join(append(chain, subtag), " -> "))
+		}
+	}
+}
+
+// Canonicalize returns the canonical form of a language tag, per RFC 5646 §4.5:
+//   - grandfathered and redundant tags are replaced by their Preferred-Value, if any;
+//   - deprecated subtags are replaced by their Preferred-Value, repeatedly if it is
+//     also deprecated, failing on cycles and on Preferred-Value missing from the registry;
+//   - a language and extlang pair, like "zh-cmn", is replaced by the extlang Preferred-Value, like "cmn",
+//     so "zh-cmn-Hans" becomes "cmn-Hans", and not "zh-Hans", which would designate
+//     the zh macrolanguage instead of Mandarin;
+//   - subtags use the recommended casing: "Hans" for scripts, "CN" for regions, lower case otherwise.
+//
+// Special subtags like "und" are valid and kept as they are. Extensions and private use
+// subtags are only lower-cased, and tags ending with a singleton, like "en-a", are rejected.
+func (r Registry) Canonicalize(tag string) (string, error) {
+	idx := r.tagIndex()
+	if e, ok := idx.tagEntry(r.Entries, tag); ok {
+		if e.PreferredValue == "" {
+			return e.Tag, nil
+		}
+		tag = e.PreferredValue
+	}
+
+	p, err := splitTag(tag)
+	if err != nil {
+		return "", err
+	}
+	var subtags []string
+	if p.language != "" {
+		if len(p.extlangs) > 0 {
+			ext, ok := idx.find(r.Entries, p.extlangs[0], TypeExtlang)
+			if !ok {
+				return "", fmt.Errorf("unknown extlang subtag %q", p.extlangs[0])
+			}
+			if len(p.extlangs) > 1 || len(ext.Prefix) == 0 || !strings.EqualFold(ext.Prefix[0], p.language) {
+				return "", fmt.Errorf("invalid extlang sequence %q for language %q", strings.Join(p.extlangs, "-"), p.language)
+			}
+			p.language = strings.ToLower(ext.PreferredValue)
+		}
+		if p.language, err = r.canonicalSubtag(idx, p.language, TypeLanguage); err != nil {
+			return "", err
+		}
+		subtags = append(subtags, p.language)
+	}
+	if p.script != "" {
+		if p.script, err = r.canonicalSubtag(idx, p.script, TypeScript); err != nil {
+			return "", err
+		}
+		subtags = append(subtags, strings.ToUpper(p.script[:1])+p.script[1:])
+	}
+	if p.region != "" {
+		if p.region, err = r.canonicalSubtag(idx, p.region, TypeRegion); err != nil {
+			return "", err
+		}
+		subtags = append(subtags, strings.ToUpper(p.region))
+	}
+	for _, v := range p.variants {
+		if v, err = r.canonicalSubtag(idx, v, TypeVariant); err != nil {
+			return "", err
+		}
+		subtags = append(subtags, v)
+	}
+	subtags = append(subtags, p.rest...)
+	return strings.Join(subtags, "-"), nil
+}
+
+// RedundantComponents returns the entries for the subtags making up a redundant tag,
+// like the "zh" language and "Hant" script entries for "zh-Hant", in tag order.
+//
+// It returns false if the tag is not a redundant entry of the registry,
+// or if one of its subtags is not in the registry.
+func (r Registry) RedundantComponents(tag string) ([]Entry, bool) {
+	idx := r.tagIndex()
+	if e, ok := idx.tagEntry(r.Entries, tag); !ok || e.Type != TypeRedundant {
+		return nil, false
+	}
+	p, err := splitTag(tag)
+	if err != nil || len(p.rest) > 0 {
+		return nil, false
+	}
+	type component struct {
+		subtag string
+		typ    Type
+	}
+	parts := []component{{p.language, TypeLanguage}}
+	for _, ext := range p.extlangs {
+		parts = append(parts, component{ext, TypeExtlang})
+	}
+	if p.script != "" {
+		parts = append(parts, component{p.script, TypeScript})
+	}
+	if p.region != "" {
+		parts = append(parts, component{p.region, TypeRegion})
+	}
+	for _, v := range p.variants {
+		parts = append(parts, component{v, TypeVariant})
+	}
+
+	components := make([]Entry, 0, len(parts))
+	for _, c := range parts {
+		e, ok := idx.find(r.Entries, c.subtag, c.typ)
+		if !ok {
+			return nil, false
+		}
+		components = append(components, e)
+	}
+	return components, true
+}
+
+// ValidateTag checks that a language tag is made of subtags of the registry, per RFC 5646 §2.2:
+//   - grandfathered and redundant tags are valid as a whole;
+//   - the language, extlang, script, region, and variant subtags must be in the registry with that type;
+//   - an extlang must follow its Prefix language, like "zh" for "zh-yue";
+//   - a variant having a Prefix must follow one of them, like "de" for "de-DE-1901", and appear once.
+//
+// Extensions and private use subtags are not checked against the registry, but each of
+// their singletons, like "u" or "x", must be followed by at least one subtag.
+func (r Registry) ValidateTag(tag string) error {
+	idx := r.tagIndex()
+	if _, ok := idx.tagEntry(r.Entries, tag); ok {
+		return nil
+	}
+
+	p, err := splitTag(tag)
+	if err != nil || p.language == "" {
+		return err
+	}
+	unknown := func(typ Type, subtag string) error {
+		return fmt.Errorf("unknown %s subtag %q in tag %q", typ, subtag, tag)
+	}
+	if _, ok := idx.find(r.Entries, p.language, TypeLanguage); !ok {
+		return unknown(TypeLanguage, p.language)
+	}
+	for _, ext := range p.extlangs {
+		e, ok := idx.find(r.Entries, ext, TypeExtlang)
+		if !ok {
+			return unknown(TypeExtlang, ext)
+		}
+		if len(e.Prefix) == 0 || !strings.EqualFold(e.Prefix[0], p.language) {
+			return fmt.Errorf("extlang subtag %q needs the prefix %q in tag %q", ext, strings.Join(e.Prefix, " or "), tag)
+		}
+	}
+	head := append([]string{p.language}, p.extlangs...)
+	if p.script != "" {
+		if _, ok := idx.find(r.Entries, p.script, TypeScript); !ok {
+			return unknown(TypeScript, p.script)
+		}
+		head = append(head, p.script)
+	}
+	if p.region != "" {
+		if _, ok := idx.find(r.Entries, p.region, TypeRegion); !ok {
+			return unknown(TypeRegion, p.region)
+		}
+		head = append(head, p.region)
+	}
+	for i, v := range p.variants {
+		e, ok := idx.find(r.Entries, v, TypeVariant)
+		if !ok {
+			return unknown(TypeVariant, v)
+		}
+		if slices.Contains(p.variants[:i], v) {
+			return fmt.Errorf("duplicate variant subtag %q in tag %q", v, tag)
+		}
+		if len(e.Prefix) > 0 && !slices.ContainsFunc(e.Prefix, func(prefix string) bool {
+			return strings.HasPrefix(strings.Join(head, "-")+"-", strings.ToLower(prefix)+"-")
+		}) {
+			return fmt.Errorf("variant subtag %q needs one of the prefixes %q in tag %q", v, e.Prefix, tag)
+		}
+		head = append(head, v)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Canonicalize(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr string
+	}{
+		{"de-DE", "de-DE", ""}, // Already canonical.
+		{"iw", "he", ""},
+		{"zh-cmn-Hans", "cmn-Hans", ""}, // The redundant tag Preferred-Value.
+		{"zh-cmn-Hans-CN", "cmn-Hans-CN", ""},
+		{"zh-yue", "yue", ""},
+		{"DE-latn-de", "de-Latn-DE", ""},
+		{"de-BU", "de-MM", ""},
+		{"i-klingon", "tlh", ""},
+		{"zh-guoyu", "cmn", ""},
+		{"zh-Hant", "zh-Hant", ""}, // A redundant tag which is not deprecated.
+		{"de-1901-x-Private", "de-1901-x-private", ""},
+		{"xx", "", `unknown language subtag "xx"`},
+		{"de-cmn", "", `invalid extlang sequence "cmn" for language "de"`},
+		{"de-u-co-phonebk", "de-u-co-phonebk", ""},
+		{"en-a", "", `singleton "a" without a following subtag in tag "en-a"`},
+		{"en-a-b-cd", "", `singleton "a" without a following subtag`},
+		{"en-x", "", `singleton "x" without a following subtag`},
+		{"x", "", `singleton "x" without a following subtag`},
+		{"x-a", "x-a", ""}, // Private use subtags may have 1 character.
+		{"en-x-abcdefghi", "", `malformed private use subtag "abcdefghi"`},
+	}
+	for _, test := range tests {
+		t.Run(test.tag, func(t *testing.T) {
+			got, err := r.Canonicalize(test.tag)
+			switch {
+			case test.wantErr == "" && (got != test.want || err != nil):
+				t.Errorf("Canonicalize(%q) = %q, %v, want %q", test.tag, got, err, test.want)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("Canonicalize(%q) = %q, %v, want error %q", test.tag, got, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry_Canonicalize_chains(t *testing.T) {
+	// language returns a language block for subtag, deprecated in favor of preferred if not empty.
+	language := func(subtag, preferred string) string {
+		block := "%%\nType: language\nSubtag: " + subtag + "\nDescription: " + subtag + "\nAdded: 2005-10-16\n"
+		if preferred != "" {
+			block += "Deprecated: 2010-01-01\nPreferred-Value: " + preferred + "\n"
+		}
+		return block
+	}
+	tests := []struct {
+		name    string
+		text    string
+		tag     string
+		want    string
+		wantErr string
+	}{
+		{"current", language("aa", ""), "aa", "aa", ""},
+		{"chain", language("aa", "bb") + language("bb", "cc") + language("cc", ""), "aa", "cc", ""},
+		{"chain from the middle", language("aa", "bb") + language("bb", "cc") + language("cc", ""), "BB", "cc", ""},
+		{"cycle", language("aa", "bb") + language("bb", "aa"), "aa", "", "cycle of Preferred-Value for language subtags aa -> bb -> aa"},
+		{"self cycle", language("aa", "aa"), "aa", "", "cycle of Preferred-Value for language subtags aa -> aa"},
+		{"missing", language("aa", "bb") + language("bb", "zz"), "aa", "", `language subtag "bb" has the unknown Preferred-Value "zz"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := mustParse(t, testRegistryHead+test.text).Canonicalize(test.tag)
+			switch {
+			case test.wantErr == "" && (got != test.want || err != nil):
+				t.Errorf("Canonicalize(%q) = %q, %v, want %q", test.tag, got, err, test.want)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("Canonicalize(%q) = %q, %v, want error %q", test.tag, got, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry_ValidateTag(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		tag     string
+		wantErr string
+	}{
+		{"de-DE", ""},
+		{"de-Latn-DE", ""},
+		{"zh-yue-TW", ""},
+		{"de-DE-1901", ""},
+		{"fr-1694acad", ""},
+		{"en-alalc97", ""}, // A variant without a Prefix.
+		{"qab-Qaab", ""},   // In private use ranges.
+		{"i-klingon", ""},
+		{"de-x-private", ""},
+		{"zz-QQ", `unknown language subtag "zz" in tag "zz-QQ"`},
+		{"de-QQ", "unknown region subtag"},
+		{"de-Zzzz", "unknown script subtag"},
+		{"de-yue", `extlang subtag "yue" needs the prefix "zh"`},
+		{"fr-1901", `variant subtag "1901" needs one of the prefixes ["de"]`},
+		{"de-1901-1901", `duplicate variant subtag "1901"`},
+		{"de-u-co-phonebk", ""},
+		{"de-DE-u-co", ""},
+		{"en-a", `singleton "a" without a following subtag in tag "en-a"`},
+		{"de-DE-u", `singleton "u" without a following subtag`},
+		{"en-u-co-x", `singleton "x" without a following subtag`},
+		{"en-x-a", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.tag, func(t *testing.T) {
+			err := r.ValidateTag(test.tag)
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("ValidateTag(%q) = %v, want nil", test.tag, err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("ValidateTag(%q) = %v, want error %q", test.tag, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry_RedundantComponents(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		tag    string
+		want   []Type
+		wantOK bool
+	}{
+		{"zh-Hant", []Type{TypeLanguage, TypeScript}, true},
+		{"ZH-HANS", []Type{TypeLanguage, TypeScript}, true},
+		{"zh-cmn-Hans", []Type{TypeLanguage, TypeExtlang, TypeScript}, true},
+		{"zh-guoyu", nil, false}, // Grandfathered, not redundant.
+		{"de-DE", nil, false},    // Not registered as a whole.
+	}
+	for _, test := range tests {
+		t.Run(test.tag, func(t *testing.T) {
+			got, ok := r.RedundantComponents(test.tag)
+			var types []Type
+			for _, e := range got {
+				types = append(types, e.Type)
+			}
+			if ok != test.wantOK || !slices.Equal(types, test.want) {
+				t.Errorf("RedundantComponents(%q) = %q with types %q, %t, want types %q, %t", test.tag, keys(got), types, ok, test.want, test.wantOK)
+			}
+			// The components are the registry entries, not copies of the tag parts.
+			for _, e := range got {
+				if len(e.Description) == 0 || !strings.EqualFold(e.Key(), strings.Split(test.tag, "-")[slices.Index(types, e.Type)]) {
+					t.Errorf("RedundantComponents(%q) returned the unexpected entry %+v", test.tag, e)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_tagIndex(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	literal := Registry{FileDate: r.FileDate, Entries: r.Entries} // Without an index cache.
+	tests := []string{"de-DE", "zh-cmn-Hans", "qab-Qaab", "QTZ", "zh-Hant", "i-klingon", "xx"}
+	for _, tag := range tests {
+		t.Run(tag, func(t *testing.T) {
+			got, err := r.Canonicalize(tag)
+			want, wantErr := literal.Canonicalize(tag)
+			if got != want || fmt.Sprint(err) != fmt.Sprint(wantErr) {
+				t.Errorf("Canonicalize(%q) = %q, %v, want %q, %v as without an index cache", tag, got, err, want, wantErr)
+			}
+			if err, want := r.ValidateTag(tag), literal.ValidateTag(tag); fmt.Sprint(err) != fmt.Sprint(want) {
+				t.Errorf("ValidateTag(%q) = %v, want %v as without an index cache", tag, err, want)
+			}
+		})
+	}
+	// The tag methods share the cached index, which lists the range entries once.
+	idx := r.index.idx
+	if !idx.indexes(r.Entries) {
+		t.Fatal("the tag methods did not build the shared index")
+	}
+	r.ValidateTag("qab")
+	if r.index.idx != idx {
+		t.Error("ValidateTag() rebuilt the shared index")
+	}
+	var ranges []string
+	for _, i := range idx.ranges {
+		ranges = append(ranges, r.Entries[i].Subtag)
+	}
+	if want := []string{"qaa..qtz", "Qaaa..Qabx"}; !slices.Equal(ranges, want) {
+		t.Errorf("index ranges are %q, want %q", ranges, want)
+	}
+}
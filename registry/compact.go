@@ -0,0 +1,88 @@
+package registry
+
+import "encoding/json"
+
+// epochDate is a Date marshalling as its EpochDays, for Options.CompactDates.
+// Date unmarshals both formats, so compact outputs decode to a Registry.
+type epochDate Date
+
+func (d epochDate) IsZero() bool {
+	return Date(d).IsZero()
+}
+
+func (d epochDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Date(d).EpochDays())
+}
+
+func (d epochDate) MarshalYAML() (any, error) {
+	return Date(d).EpochDays(), nil
+}
+
+// compactEntry is an Entry marshalling its dates as epochDate values, with the same keys.
+type compactEntry struct {
+	Added          epochDate `json:"added" yaml:"added"`
+	Comments       string    `json:"comments,omitempty" yaml:"comments,omitempty"`
+	Deprecated     epochDate `json:"deprecated,omitzero" yaml:"deprecated,omitempty"`
+	Description    []string  `json:"description,omitempty" yaml:"description,omitempty"`
+	MacroLanguage  string    `json:"macro-language,omitempty" yaml:"macro-language,omitempty"`
+	PreferredValue string    `json:"preferred-value,omitempty" yaml:"preferred-value,omitempty"`
+	Prefix         []string  `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Scope          Scope     `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Subtag         string    `json:"subtag,omitempty" yaml:"subtag,omitempty"`
+	SuppressScript Script    `json:"suppress-script,omitzero" yaml:"suppress-script,omitempty"`
+	Tag            string    `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Type           Type      `json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+func newCompactEntry(e Entry) compactEntry {
+	return compactEntry{
+		Added:          epochDate(e.Added),
+		Comments:       e.Comments,
+		Deprecated:     epochDate(e.Deprecated),
+		Description:    e.Description,
+		MacroLanguage:  e.MacroLanguage,
+		PreferredValue: e.PreferredValue,
+		Prefix:         e.Prefix,
+		Scope:          e.Scope,
+		Subtag:         e.Subtag,
+		SuppressScript: e.SuppressScript,
+		Tag:            e.Tag,
+		Type:           e.Type,
+	}
+}
+
+// compactEntries converts entries to compactEntry values, keeping a nil list nil
+// so that it marshals like the original.
+func compactEntries(entries []Entry) []compactEntry {
+	if entries == nil {
+		return nil
+	}
+	res := make([]compactEntry, len(entries))
+	for i, e := range entries {
+		res[i] = newCompactEntry(e)
+	}
+	return res
+}
+
+// compactRegistry is a Registry marshalling its dates as epochDate values, with the same keys.
+type compactRegistry struct {
+	FileDate epochDate      `json:"file-date" yaml:"file-date"`
+	Entries  []compactEntry `json:"entries" yaml:"entries"`
+}
+
+// document returns the value to marshal for a registry: the registry itself,
+// or its compactRegistry with opts.CompactDates.
+func document(r Registry, opts Options) any {
+	if !opts.CompactDates {
+		return r
+	}
+	return compactRegistry{FileDate: epochDate(r.FileDate), Entries: compactEntries(r.Entries)}
+}
+
+// entryDocument returns the value to marshal for an entry, like document.
+func entryDocument(e Entry, opts Options) any {
+	if !opts.CompactDates {
+		return e
+	}
+	return newCompactEntry(e)
+}
@@ -0,0 +1,186 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Operations of a Change.
+const (
+	OpAdd    = "add"
+	OpRemove = "remove"
+	OpModify = "modify"
+)
+
+// Patch describes the changes turning a registry into a newer one, as built by Diff
+// and applied by ApplyPatch.
+type Patch struct {
+	FileDate Date     `json:"file-date" yaml:"file-date"` // Of the newer registry.
+	Changes  []Change `json:"changes" yaml:"changes"`
+}
+
+// Change describes the change of one entry, identified by its Type and Key.
+//
+// Fields are keyed by their name in the IANA format, like "Preferred-Value", and hold
+// all the values of the field, like the Description lines: for an added entry, all its
+// fields; for a modified one, only the changed fields, an empty list removing the field.
+// Old holds the previous values of the changed fields, to detect conflicting changes.
+type Change struct {
+	Op     string              `json:"op" yaml:"op"`
+	Type   Type                `json:"type" yaml:"type"`
+	Key    string              `json:"key" yaml:"key"`
+	Fields map[string][]string `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Old    map[string][]string `json:"old,omitempty" yaml:"old,omitempty"`
+}
+
+// diffKey identifies an entry in a registry, since subtags are only unique within a type.
+type diffKey struct {
+	typ Type
+	key string
+}
+
+func newDiffKey(typ Type, key string) diffKey {
+	return diffKey{typ, strings.ToLower(key)}
+}
+
+// fieldValues maps the names of the non-empty fields of an entry to their values.
+func (e Entry) fieldValues() map[string][]string {
+	values := make(map[string][]string)
+	for _, f := range e.fields() {
+		values[f.key] = append(values[f.key], f.value)
+	}
+	return values
+}
+
+// Diff returns the patch turning the from registry into the to registry: the removed
+// entries in from order, then the added and modified entries in to order.
+func Diff(from, to Registry) Patch {
+	p := Patch{FileDate: to.FileDate, Changes: []Change{}}
+	toIdx := make(map[diffKey]Entry, len(to.Entries))
+	for _, e := range to.Entries {
+		toIdx[newDiffKey(e.Type, e.Key())] = e
+	}
+	fromIdx := make(map[diffKey]Entry, len(from.Entries))
+	for _, e := range from.Entries {
+		k := newDiffKey(e.Type, e.Key())
+		fromIdx[k] = e
+		if _, ok := toIdx[k]; !ok {
+			p.Changes = append(p.Changes, Change{Op: OpRemove, Type: e.Type, Key: e.Key()})
+		}
+	}
+	for _, e := range to.Entries {
+		prev, ok := fromIdx[newDiffKey(e.Type, e.Key())]
+		if !ok {
+			p.Changes = append(p.Changes, Change{Op: OpAdd, Type: e.Type, Key: e.Key(), Fields: e.fieldValues()})
+			continue
+		}
+		c := Change{Op: OpModify, Type: e.Type, Key: e.Key(), Fields: e.fieldValues(), Old: prev.fieldValues()}
+		for name, values := range c.Fields {
+			if slices.Equal(values, c.Old[name]) {
+				delete(c.Fields, name)
+				delete(c.Old, name)
+			}
+		}
+		for name := range c.Old {
+			if _, ok := c.Fields[name]; !ok {
+				c.Fields[name] = []string{}
+			}
+		}
+		if len(c.Fields) > 0 {
+			p.Changes = append(p.Changes, c)
+		}
+	}
+	return p
+}
+
+// changeError builds an error about the i-th change of a patch.
+func changeError(i int, c Change, format string, args ...any) error {
+	return fmt.Errorf("change %d (%s %s %s): %s", i, c.Op, c.Type, c.Key, fmt.Sprintf(format, args...))
+}
+
+// ApplyPatch applies the changes of a patch built by Diff, and takes its File-Date.
+// Added entries are appended in patch order.
+//
+// The patch is applied entirely or not at all: it fails without modifying the registry
+// if an entry to remove or modify is missing, if an entry to add already exists,
+// or if the current values of a modified entry do not match the Old ones.
+func (r *Registry) ApplyPatch(p Patch) error {
+	entries := slices.Clone(r.Entries)
+	idx := make(map[diffKey]int, len(entries))
+	for i, e := range entries {
+		idx[newDiffKey(e.Type, e.Key())] = i
+	}
+	removed := make(map[int]bool)
+	for i, c := range p.Changes {
+		k := newDiffKey(c.Type, c.Key)
+		pos, ok := idx[k]
+		if c.Op == OpAdd {
+			if ok {
+				return changeError(i, c, "entry already exists")
+			}
+		} else if !ok || removed[pos] {
+			return changeError(i, c, "no such entry")
+		}
+		switch c.Op {
+		case OpRemove:
+			removed[pos] = true
+		case OpAdd:
+			e, err := parseFields(c.Fields)
+			if err != nil {
+				return changeError(i, c, "%v", err)
+			}
+			idx[k] = len(entries)
+			entries = append(entries, e)
+		case OpModify:
+			values := entries[pos].fieldValues()
+			for name, old := range c.Old {
+				if !slices.Equal(values[name], old) {
+					return changeError(i, c, "field %s is %q, not %q", name, values[name], old)
+				}
+			}
+			for name, vs := range c.Fields {
+				values[name] = vs
+			}
+			e, err := parseFields(values)
+			if err != nil {
+				return changeError(i, c, "%v", err)
+			}
+			entries[pos] = e
+		default:
+			return changeError(i, c, "unknown operation")
+		}
+	}
+	if len(removed) > 0 {
+		kept := entries[:0]
+		for i, e := range entries {
+			if !removed[i] {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+	r.FileDate, r.Entries = p.FileDate, entries
+	return nil
+}
+
+// parseFields parses an entry from its fields, keyed by name like in a Change.
+func parseFields(fields map[string][]string) (Entry, error) {
+	lexed := make(map[string][]string, len(fields))
+	for name, values := range fields {
+		if len(values) > 0 {
+			lexed[strings.ToLower(name)] = values
+		}
+	}
+	if len(lexed) == 0 {
+		return Entry{}, errors.New("no fields")
+	}
+	e, err := parseBlock(lexed)
+	// Patch changes are not registry blocks, so their block index is meaningless.
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		return Entry{}, pe.Err
+	}
+	return e, err
+}
@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDiff_ApplyPatch(t *testing.T) {
+	from := mustParse(t, `File-Date: 2023-01-01
+%%
+Type: language
+Subtag: de
+Description: German
+Added: 2005-10-16
+%%
+Type: language
+Subtag: iw
+Description: Hebrew
+Added: 2005-10-16
+Comments: To be deprecated
+%%
+Type: language
+Subtag: xx
+Description: Removed
+Added: 2005-10-16
+`)
+	to := mustParse(t, `File-Date: 2023-08-02
+%%
+Type: language
+Subtag: de
+Description: German
+Description: Deutsch
+Added: 2005-10-16
+%%
+Type: language
+Subtag: he
+Description: Hebrew
+Added: 2005-10-16
+%%
+Type: language
+Subtag: iw
+Description: Hebrew
+Added: 2005-10-16
+Deprecated: 1989-01-01
+Preferred-Value: he
+`)
+	// Round-trip the patch through YAML, as written by -format patch.
+	bs, err := yaml.Marshal(Diff(*from, *to))
+	if err != nil {
+		t.Fatalf("failed encoding patch: %v", err)
+	}
+	var p Patch
+	if err = yaml.Unmarshal(bs, &p); err != nil {
+		t.Fatalf("failed decoding patch: %v\n%s", err, bs)
+	}
+	if p.FileDate.String() != "2023-08-02" {
+		t.Errorf("patch has File-Date %s, want 2023-08-02", p.FileDate)
+	}
+	want := []Change{
+		{Op: OpRemove, Type: TypeLanguage, Key: "xx"},
+		{Op: OpModify, Type: TypeLanguage, Key: "de",
+			Fields: map[string][]string{"Description": {"German", "Deutsch"}},
+			Old:    map[string][]string{"Description": {"German"}}},
+		{Op: OpAdd, Type: TypeLanguage, Key: "he", Fields: map[string][]string{
+			"Type": {"language"}, "Subtag": {"he"}, "Description": {"Hebrew"}, "Added": {"2005-10-16"}}},
+		{Op: OpModify, Type: TypeLanguage, Key: "iw",
+			Fields: map[string][]string{"Deprecated": {"1989-01-01"}, "Preferred-Value": {"he"}, "Comments": {}},
+			Old:    map[string][]string{"Comments": {"To be deprecated"}}},
+	}
+	if len(p.Changes) != len(want) {
+		t.Fatalf("patch has changes %+v, want %+v", p.Changes, want)
+	}
+	for i, c := range p.Changes {
+		t.Run(c.Op+"/"+c.Key, func(t *testing.T) {
+			w := want[i]
+			if c.Op != w.Op || c.Type != w.Type || c.Key != w.Key ||
+				!maps.EqualFunc(c.Fields, w.Fields, slices.Equal) || !maps.EqualFunc(c.Old, w.Old, slices.Equal) {
+				t.Errorf("change %d = %+v, want %+v", i, c, w)
+			}
+		})
+	}
+
+	if err = from.ApplyPatch(p); err != nil {
+		t.Fatalf("ApplyPatch() failed: %v", err)
+	}
+	// Added entries are appended.
+	if got, want := keys(from.Entries), []string{"de", "iw", "he"}; !slices.Equal(got, want) || !from.FileDate.Equal(to.FileDate) {
+		t.Errorf("patched registry has %s %q, want %s %q", from.FileDate, got, to.FileDate, want)
+	}
+	if Diff(*from, *to).Changes == nil || len(Diff(*from, *to).Changes) != 0 {
+		t.Errorf("patched registry differs from the newer one: %+v", Diff(*from, *to).Changes)
+	}
+	// Applying again conflicts, and leaves the registry as it was.
+	if err = from.ApplyPatch(p); err == nil {
+		t.Errorf("ApplyPatch() applied twice = nil, want a conflict")
+	}
+	if got := keys(from.Entries); len(got) != 3 {
+		t.Errorf("failed ApplyPatch() modified the registry to %q", got)
+	}
+}
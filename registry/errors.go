@@ -0,0 +1,35 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMalformedBlock matches, with errors.Is, the errors returned for a registry block which cannot be parsed.
+var ErrMalformedBlock = errors.New("malformed block")
+
+// ErrTooManyEntries matches, with errors.Is, the errors returned for a registry with more than Options.MaxEntries entries.
+var ErrTooManyEntries = errors.New("registry has too many entries")
+
+// ParseError describes a registry block which cannot be parsed.
+type ParseError struct {
+	Block int    // Index of the block in the registry, the File-Date block being 0.
+	Key   string // Lower-case key of the malformed field, if any.
+	Value string // Value of the malformed field, multiple values being separated by newlines.
+	Err   error
+}
+
+// Error implements error.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("block %d: %v", e.Block, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrMalformedBlock.
+func (e *ParseError) Is(target error) bool {
+	return target == ErrMalformedBlock
+}
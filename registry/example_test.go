@@ -0,0 +1,26 @@
+package registry_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+func ExampleParse() {
+	r, err := registry.Parse(strings.NewReader(`File-Date: 2023-08-02
+%%
+Type: language
+Subtag: de
+Description: German
+Added: 2005-10-16
+Suppress-Script: Latn
+`))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	e := r.Entries[0]
+	fmt.Println(r.FileDate, len(r.Entries), e.Type, e.Subtag, e.Description[0], e.SuppressScript)
+	// Output: 2023-08-02 1 language de German Latn
+}
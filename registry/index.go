@@ -0,0 +1,302 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// indexKey returns the key under which an entry is indexed: its lower-cased Key.
+func indexKey(e Entry) string {
+	return strings.ToLower(e.Key())
+}
+
+// lookupKey returns the index key for a subtag or tag, rejecting non-ASCII ones.
+//
+// Subtags and tags are ASCII, per RFC 5646 §2.1, so lower-casing is enough to compare them
+// case-insensitively, but Unicode lower-casing could map non-ASCII input to ASCII,
+// like "K" (Kelvin sign) to "k".
+func lookupKey(subtag string) (string, bool) {
+	for i := 0; i < len(subtag); i++ {
+		if subtag[i] >= utf8.RuneSelf {
+			return "", false
+		}
+	}
+	return strings.ToLower(subtag), true
+}
+
+// Index maps the lower-cased subtags and tags in the registry to their entries.
+//
+// Since subtags are only unique within a type, a key may have multiple entries,
+// like "cmn" which is both a language and an extlang: these are always listed
+// in registry order, i.e. the order of the source file.
+func (r Registry) Index() map[string][]Entry {
+	idx := make(map[string][]Entry, len(r.Entries))
+	for _, e := range r.Entries {
+		k := indexKey(e)
+		idx[k] = append(idx[k], e)
+	}
+	return idx
+}
+
+// subtagIndex maps the index keys of entries to their positions in Registry.Entries.
+type subtagIndex struct {
+	subtags map[string][]int // In registry order, since subtags are only unique within a type.
+	tags    map[string]int   // Grandfathered and redundant tags.
+	ranges  []int            // Entries for ranges of subtags, like "qaa..qtz", in registry order.
+
+	// The first indexed entry and the number of entries, to detect a reassigned or resized Registry.Entries.
+	first *Entry
+	count int
+}
+
+func newSubtagIndex(entries []Entry) *subtagIndex {
+	idx := &subtagIndex{subtags: make(map[string][]int), tags: make(map[string]int), count: len(entries)}
+	if len(entries) > 0 {
+		idx.first = &entries[0]
+	}
+	for i, e := range entries {
+		if e.Subtag != "" {
+			k := strings.ToLower(e.Subtag)
+			idx.subtags[k] = append(idx.subtags[k], i)
+			if strings.Contains(k, "..") {
+				idx.ranges = append(idx.ranges, i)
+			}
+		} else if _, ok := idx.tags[strings.ToLower(e.Tag)]; !ok {
+			idx.tags[strings.ToLower(e.Tag)] = i
+		}
+	}
+	return idx
+}
+
+// indexes reports whether idx was built for entries. Entries modified in place are not detected.
+func (idx *subtagIndex) indexes(entries []Entry) bool {
+	return idx != nil && idx.count == len(entries) && (len(entries) == 0 || idx.first == &entries[0])
+}
+
+// subtag returns the position of the entry with the given index key and type, or of any type if typ is empty.
+func (idx *subtagIndex) subtag(entries []Entry, key string, typ Type) (int, bool) {
+	for _, i := range idx.subtags[key] {
+		if typ == "" || entries[i].Type == typ {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// find returns the entry with the given lower-cased subtag and type,
+// including entries for ranges containing it, like "qaa..qtz" for "qab".
+func (idx *subtagIndex) find(entries []Entry, subtag string, typ Type) (Entry, bool) {
+	if i, ok := idx.subtag(entries, subtag, typ); ok {
+		return entries[i], true
+	}
+	for _, i := range idx.ranges {
+		if e := entries[i]; e.Type == typ && rangeContains(e.Subtag, subtag) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// tagEntry returns the grandfathered or redundant entry with the given tag, of any case.
+func (idx *subtagIndex) tagEntry(entries []Entry, tag string) (Entry, bool) {
+	i, ok := idx.tags[strings.ToLower(tag)]
+	if !ok || entries[i].Type != TypeGrandfathered && entries[i].Type != TypeRedundant {
+		return Entry{}, false
+	}
+	return entries[i], true
+}
+
+// indexCache holds the subtagIndex of a registry. It is shared by the copies of the registry,
+// so that the methods on Registry values build it once too.
+type indexCache struct {
+	mu  sync.Mutex
+	idx *subtagIndex
+}
+
+// lookupIndex returns the index of the registry entries, building it on first use,
+// and again when Entries was reassigned or resized since. It returns nil for registries
+// without an indexCache, like those not read by ParseWith, which lookups scan instead.
+func (r Registry) lookupIndex() *subtagIndex {
+	if r.index == nil {
+		return nil
+	}
+	r.index.mu.Lock()
+	defer r.index.mu.Unlock()
+	if !r.index.idx.indexes(r.Entries) {
+		r.index.idx = newSubtagIndex(r.Entries)
+	}
+	return r.index.idx
+}
+
+// BuildIndex indexes the entries for the fast, case-insensitive lookups of BySubtag, ByTag,
+// and BySubtagAndType, which also use it in Lookup and Has.
+//
+// The lookup methods build the index on first use, and rebuild it after Entries is reassigned,
+// like with the result of Filter, or after entries are added or removed. Only changing
+// the subtag or tag of entries in place requires calling BuildIndex again.
+func (r *Registry) BuildIndex() {
+	if r.index == nil {
+		r.index = &indexCache{}
+	}
+	r.index.mu.Lock()
+	defer r.index.mu.Unlock()
+	r.index.idx = newSubtagIndex(r.Entries)
+}
+
+// tagIndex is lookupIndex for the language tag methods, which need an index even for
+// registries without an indexCache: such registries get one built for the call.
+func (r Registry) tagIndex() *subtagIndex {
+	if idx := r.lookupIndex(); idx != nil {
+		return idx
+	}
+	return newSubtagIndex(r.Entries)
+}
+
+// pointerIndex is lookupIndex for the pointer methods, which can add the missing indexCache.
+func (r *Registry) pointerIndex() *subtagIndex {
+	if r.index == nil {
+		r.index = &indexCache{}
+	}
+	return r.lookupIndex()
+}
+
+// BySubtag returns the first entry with the given subtag, of any type.
+// Since subtags are only unique within a type, use BySubtagAndType to disambiguate them.
+func (r *Registry) BySubtag(subtag string) (*Entry, bool) {
+	return r.BySubtagAndType(subtag, "")
+}
+
+// BySubtagAndType returns the entry with the given subtag and type, or of any type if typ is empty.
+func (r *Registry) BySubtagAndType(subtag string, typ Type) (*Entry, bool) {
+	key, ok := lookupKey(subtag)
+	if !ok {
+		return nil, false
+	}
+	i, ok := r.pointerIndex().subtag(r.Entries, key, typ)
+	if !ok {
+		return nil, false
+	}
+	return &r.Entries[i], true
+}
+
+// ByTag returns the grandfathered or redundant entry with the given tag.
+func (r *Registry) ByTag(tag string) (*Entry, bool) {
+	key, ok := lookupKey(tag)
+	if !ok {
+		return nil, false
+	}
+	i, ok := r.pointerIndex().tags[key]
+	if !ok {
+		return nil, false
+	}
+	return &r.Entries[i], true
+}
+
+// Lookup returns the entry with the given subtag, or tag for grandfathered and redundant
+// entries, and type. The subtag is case-insensitive, and never matches if it is not ASCII.
+// An empty typ matches entries of any type, like for BySubtagAndType, with or without an index.
+func (r Registry) Lookup(subtag string, typ Type) (Entry, bool) {
+	key, ok := lookupKey(subtag)
+	if !ok {
+		return Entry{}, false
+	}
+	if idx := r.lookupIndex(); idx != nil {
+		var i int
+		switch typ {
+		case TypeGrandfathered, TypeRedundant:
+			i, ok = idx.tags[key]
+			ok = ok && r.Entries[i].Type == typ
+		case "":
+			if i, ok = idx.subtag(r.Entries, key, typ); !ok {
+				i, ok = idx.tags[key]
+			}
+		default:
+			i, ok = idx.subtag(r.Entries, key, typ)
+		}
+		if !ok {
+			return Entry{}, false
+		}
+		return r.Entries[i], true
+	}
+	for _, e := range r.Entries {
+		if (typ == "" || e.Type == typ) && indexKey(e) == key {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// DisplayName returns the best name to display for a subtag of the given type:
+// its first Description or, for a deprecated subtag having a Preferred-Value,
+// the first Description of the preferred entry, with a note about the deprecation.
+//
+// It returns false if the subtag is not in the registry.
+func (r Registry) DisplayName(subtag string, typ Type) (string, bool) {
+	e, ok := r.Lookup(subtag, typ)
+	if !ok {
+		return "", false
+	}
+	name := firstDescription(e)
+	if e.Deprecated.IsZero() || e.PreferredValue == "" {
+		return name, true
+	}
+	// The preferred value of a tag entry is usually a language, like "jbo" for "art-lojban".
+	preferredType := typ
+	if e.Tag != "" {
+		preferredType = TypeLanguage
+	}
+	if p, ok := r.Lookup(e.PreferredValue, preferredType); ok {
+		name = firstDescription(p)
+	}
+	return fmt.Sprintf("%s (%s deprecated, use %s)", name, e.Key(), e.PreferredValue), true
+}
+
+// SuppressScript returns the Suppress-Script of a language, i.e. the script to omit from tags
+// using its subtag, like "Latn" for "en". It returns false if the language is not in the registry,
+// or has none.
+func (r Registry) SuppressScript(lang string) (Script, bool) {
+	e, ok := r.Lookup(lang, TypeLanguage)
+	if !ok || e.SuppressScript.IsZero() {
+		return Script{}, false
+	}
+	return e.SuppressScript, true
+}
+
+// DefaultScript returns the Suppress-Script of a language like SuppressScript, as a string,
+// since it is the script implied by the language subtag.
+func (r Registry) DefaultScript(lang string) (string, bool) {
+	s, ok := r.SuppressScript(lang)
+	return s.String(), ok
+}
+
+// firstDescription returns the first Description of an entry, or its key if it has none.
+func firstDescription(e Entry) string {
+	if len(e.Description) == 0 {
+		return e.Key()
+	}
+	return e.Description[0]
+}
+
+// Has reports whether the registry has an entry of any type for the given subtag, or tag for
+// grandfathered and redundant entries, compared case-insensitively. Unlike Lookup, it needs no type.
+// Like for Lookup, non-ASCII subtags never match.
+func (r Registry) Has(subtag string) bool {
+	key, ok := lookupKey(subtag)
+	if !ok {
+		return false
+	}
+	if idx := r.lookupIndex(); idx != nil {
+		if _, ok = idx.subtag(r.Entries, key, ""); !ok {
+			_, ok = idx.tags[key]
+		}
+		return ok
+	}
+	for _, e := range r.Entries {
+		if indexKey(e) == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Index(t *testing.T) {
+	idx := parseTestdata(t, Options{}).Index()
+	tests := []struct {
+		key  string
+		want []Type
+	}{
+		{"cmn", []Type{TypeLanguage, TypeExtlang}}, // Collisions are in registry order.
+		{"yue", []Type{TypeLanguage, TypeExtlang}},
+		{"de", []Type{TypeLanguage, TypeRegion}},
+		{"en", []Type{TypeLanguage}},
+		{"zh-hans", []Type{TypeRedundant}}, // Keys are lower-cased.
+		{"zh-Hans", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			var got []Type
+			for _, e := range idx[test.key] {
+				got = append(got, e.Type)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("Index()[%q] has types %q, want %q", test.key, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_DisplayName(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		subtag string
+		typ    Type
+		want   string
+		wantOK bool
+	}{
+		{"de", TypeLanguage, "German", true},
+		{"DE", TypeRegion, "Germany", true},
+		{"iw", TypeLanguage, "Hebrew (iw deprecated, use he)", true},
+		{"BU", TypeRegion, "Myanmar (BU deprecated, use MM)", true},
+		{"art-lojban", TypeGrandfathered, "Lojban (art-lojban deprecated, use jbo)", true},
+		{"de", TypeScript, "", false},
+		{"xx", TypeLanguage, "", false},
+	}
+	for _, test := range tests {
+		t.Run(string(test.typ)+"/"+test.subtag, func(t *testing.T) {
+			got, ok := r.DisplayName(test.subtag, test.typ)
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("DisplayName(%q, %s) = %q, %t, want %q, %t", test.subtag, test.typ, got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestRegistry_Has(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	indexed := parseTestdata(t, Options{})
+	indexed.BuildIndex()
+	tests := []struct {
+		subtag string
+		want   bool
+	}{
+		{"de", true},
+		{"DE", true},
+		{"latn", true},
+		{"ZH-HANT", true}, // A tag.
+		{"1901", true},
+		{"xx", false},
+		{"", false},
+		{"dé", false}, // Not ASCII.
+	}
+	for _, test := range tests {
+		t.Run(test.subtag, func(t *testing.T) {
+			if got := r.Has(test.subtag); got != test.want {
+				t.Errorf("Has(%q) = %t, want %t", test.subtag, got, test.want)
+			}
+			if got := indexed.Has(test.subtag); got != test.want {
+				t.Errorf("Has(%q) = %t with an index, want %t", test.subtag, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Lookup_nonASCII(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		subtag string
+		typ    Type
+		want   bool
+	}{
+		{"de", TypeLanguage, true},
+		{"DE", TypeLanguage, true},
+		{"Latn", TypeScript, true},
+		{"LATN", TypeScript, true},
+		{"Lȧtn", TypeScript, false},
+		{"ZH-HANT", TypeRedundant, true},
+		{"Klingon", TypeLanguage, false}, // The Kelvin sign lower-cases to "k".
+		{"i-Klingon", TypeGrandfathered, false},
+		{"dé", TypeLanguage, false},
+	}
+	for _, test := range tests {
+		t.Run(test.subtag, func(t *testing.T) {
+			if _, ok := r.Lookup(test.subtag, test.typ); ok != test.want {
+				t.Errorf("Lookup(%q, %s) found %t, want %t", test.subtag, test.typ, ok, test.want)
+			}
+			if test.typ == TypeGrandfathered || test.typ == TypeRedundant {
+				if _, ok := r.ByTag(test.subtag); ok != test.want {
+					t.Errorf("ByTag(%q) found %t, want %t", test.subtag, ok, test.want)
+				}
+			} else if _, ok := r.BySubtagAndType(test.subtag, test.typ); ok != test.want {
+				t.Errorf("BySubtagAndType(%q, %s) found %t, want %t", test.subtag, test.typ, ok, test.want)
+			}
+		})
+	}
+	if key, ok := lookupKey("K"); ok {
+		t.Errorf("lookupKey(Kelvin sign) = %q, true, want false", key)
+	}
+}
+
+func TestRegistry_lookupIndex_stale(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(r *Registry)
+		subtag string
+		typ    Type
+		want   bool
+	}{
+		{"reassigned", func(r *Registry) { r.Entries = r.ByType(TypeRegion) }, "hans", "", false},
+		{"reassigned, still present", func(r *Registry) { r.Entries = r.ByType(TypeRegion) }, "de", TypeRegion, true},
+		{"truncated", func(r *Registry) { r.Entries = r.Entries[:1] }, "zh-hans", "", false},
+		{"appended", func(r *Registry) {
+			r.Entries = append(r.Entries, Entry{Type: TypeLanguage, Subtag: "qaa-added"})
+		}, "qaa-added", TypeLanguage, true},
+		{"emptied", func(r *Registry) { r.Entries = nil }, "en", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := parseTestdata(t, Options{})
+			r.BuildIndex()
+			test.update(r)
+			if e, ok := r.BySubtagAndType(test.subtag, test.typ); ok != test.want || ok && !strings.EqualFold(e.Subtag, test.subtag) {
+				t.Errorf("BySubtagAndType(%q, %q) = %+v, %t, want %t", test.subtag, test.typ, e, ok, test.want)
+			}
+			if _, ok := r.Lookup(test.subtag, test.typ); ok != test.want {
+				t.Errorf("Lookup(%q, %q) = %t, want %t", test.subtag, test.typ, ok, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_lookupIndex_shared(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	if r.index == nil {
+		t.Fatal("ParseWith() returned a registry without an index cache")
+	}
+	tests := []struct {
+		subtag string
+		typ    Type
+		want   bool
+	}{
+		{"DE", TypeRegion, true},
+		{"zh-hans", TypeRedundant, true},
+		{"art-lojban", TypeGrandfathered, true},
+		{"de", TypeScript, false},
+	}
+	for _, test := range tests {
+		t.Run(string(test.typ)+"/"+test.subtag, func(t *testing.T) {
+			if _, ok := r.Lookup(test.subtag, test.typ); ok != test.want {
+				t.Errorf("Lookup(%q, %s) = %t, want %t", test.subtag, test.typ, ok, test.want)
+			}
+			if ok := r.Has(test.subtag); !ok && test.want {
+				t.Errorf("Has(%q) = false, want true", test.subtag)
+			}
+		})
+	}
+	// The value receivers built the index in the cache shared with r.
+	if !r.index.idx.indexes(r.Entries) {
+		t.Error("Lookup() did not build the shared index")
+	}
+}
+
+func TestRegistry_Lookup_anyType(t *testing.T) {
+	parsed := parseTestdata(t, Options{})
+	literal := Registry{FileDate: parsed.FileDate, Entries: parsed.Entries} // Without an index cache.
+	tests := []struct {
+		subtag   string
+		typ      Type
+		wantType Type
+		wantOK   bool
+	}{
+		{"de", "", TypeLanguage, true}, // The first in registry order.
+		{"DE", TypeRegion, TypeRegion, true},
+		{"latn", "", TypeScript, true},
+		{"zh-hant", "", TypeRedundant, true},
+		{"i-klingon", "", TypeGrandfathered, true},
+		{"xx", "", "", false},
+	}
+	for _, test := range tests {
+		t.Run(string(test.typ)+"/"+test.subtag, func(t *testing.T) {
+			for name, r := range map[string]Registry{"parsed": *parsed, "literal": literal} {
+				if e, ok := r.Lookup(test.subtag, test.typ); e.Type != test.wantType || ok != test.wantOK {
+					t.Errorf("%s Lookup(%q, %q) = %s, %t, want %s, %t", name, test.subtag, test.typ, e.Type, ok, test.wantType, test.wantOK)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_DefaultScript(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		lang   string
+		want   string
+		wantOK bool
+	}{
+		{"en", "Latn", true},
+		{"EN", "Latn", true},
+		{"he", "Hebr", true},
+		{"zh", "", false},    // Written in several scripts.
+		{"DE", "Latn", true}, // The German language, not the region.
+		{"xx", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.lang, func(t *testing.T) {
+			if got, ok := r.DefaultScript(test.lang); got != test.want || ok != test.wantOK {
+				t.Errorf("DefaultScript(%q) = %q, %t, want %q, %t", test.lang, got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestRegistry_SuppressScript(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		lang   string
+		want   string
+		wantOK bool
+	}{
+		{"en", "Latn", true},
+		{"iw", "Hebr", true}, // Deprecated languages keep theirs.
+		{"zh", "", false},
+		{"Latn", "", false}, // A script, not a language.
+		{"xx", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.lang, func(t *testing.T) {
+			got, ok := r.SuppressScript(test.lang)
+			if ok != test.wantOK || got.String() != test.want || fmt.Sprint(got) != test.want {
+				t.Errorf("SuppressScript(%q) = %v, %t, want %q, %t", test.lang, got, ok, test.want, test.wantOK)
+			}
+			if !ok && !got.IsZero() {
+				t.Errorf("SuppressScript(%q) = %v, want the zero Script", test.lang, got)
+			}
+		})
+	}
+}
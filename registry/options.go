@@ -0,0 +1,63 @@
+package registry
+
+// DefaultFoldWidth is the column at which WriteRegistry folds long lines by default.
+// It is 72 rather than the 80 columns of terminals, since the registry published by IANA
+// keeps its lines within 72 columns, and written registries should look like it.
+const DefaultFoldWidth = 72
+
+// DefaultDescriptionJoin is the separator used by default to join multiple descriptions.
+const DefaultDescriptionJoin = "; "
+
+// Options tunes the parsing, validation, and writing of a registry.
+type Options struct {
+	// Color highlights the text and grep formats with ANSI escape sequences, for terminals:
+	// types are dimmed, and deprecated entries are red.
+	Color bool
+
+	// CompactDates makes the yaml, json, and bytype formats write dates as their EpochDays
+	// instead of date-only strings, for space-constrained outputs.
+	CompactDates bool
+
+	// DescriptionJoin separates multiple descriptions in the formats using a single
+	// string for them: text and csv. Empty means DefaultDescriptionJoin.
+	DescriptionJoin string
+
+	// Envelope wraps the entries of the JSON format in an object also providing
+	// their Registry.Source, File-Date, and count.
+	Envelope bool
+
+	// FailFast makes ParseWith stop at the first malformed block, instead of reporting them all.
+	FailFast bool
+
+	// Faithful makes ParseWith keep the line breaks of values continued on multiple lines,
+	// like Comments, instead of joining their lines by spaces, so that WriteRegistry reproduces them.
+	Faithful bool
+
+	// FoldWidth is the column at which WriteRegistryWith folds long field values.
+	// Zero means DefaultFoldWidth.
+	FoldWidth int
+
+	// Lenient skips the checks for fields RFC 5646 requires, to accept trimmed registries
+	// carrying only some fields, like Type, Subtag, and Description.
+	Lenient bool
+
+	// MaxEntries is the number of entry blocks beyond which ParseWith fails with ErrTooManyEntries,
+	// to guard against pathological inputs. Zero means no limit.
+	MaxEntries int
+}
+
+// foldWidth returns the FoldWidth to use, applying the default.
+func (o Options) foldWidth() int {
+	if o.FoldWidth <= 0 {
+		return DefaultFoldWidth
+	}
+	return o.FoldWidth
+}
+
+// descriptionJoin returns the DescriptionJoin to use, applying the default.
+func (o Options) descriptionJoin() string {
+	if o.DescriptionJoin == "" {
+		return DefaultDescriptionJoin
+	}
+	return o.DescriptionJoin
+}
@@ -0,0 +1,341 @@
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats supported by NewEncoder.
+const (
+	FormatYAML        = "yaml"         // The whole registry, as in the README.
+	FormatByType      = "bytype"       // Entries keyed by type, then subtag or tag.
+	FormatGoMap       = "gomap"        // Go source for a map of language subtags to descriptions.
+	FormatJSON        = "json"         // The whole registry, like the yaml format.
+	FormatText        = "text"         // One line per entry, with its type, subtag or tag, and descriptions.
+	FormatGrep        = "grep"         // Tab-separated subtag or tag, type, and description, one line per description.
+	FormatEnv         = "env"          // LANG_DE=German lines for the names of languages, like .env files.
+	FormatCSV         = "csv"          // One row per entry, with a header row, for spreadsheets.
+	FormatScriptLangs = "script-langs" // Suppress-Script codes mapped to the languages suppressing them.
+	FormatRegistry    = "registry"     // The IANA text format read by Parse, as written by WriteRegistryWith.
+)
+
+// Encoder writes one registry to its output, in a given format.
+type Encoder func(Registry) error
+
+// envelope wraps the entries of the JSON format with metadata about their source,
+// as for API responses.
+// Its dates and entries hold the values returned by document for them.
+type envelope struct {
+	Source   string `json:"source"`
+	FileDate any    `json:"fileDate"`
+	Count    int    `json:"count"`
+	Entries  any    `json:"entries"`
+}
+
+// NewEncoder builds an encoder writing to w in the given format.
+//
+// Successive calls on the same encoder write successive YAML documents, or JSON values.
+// With opts.CompactDates, the yaml, json, and bytype formats write dates as EpochDays.
+func NewEncoder(w io.Writer, format string, opts Options) (Encoder, error) {
+	if opts.Envelope && format != FormatJSON {
+		return nil, fmt.Errorf("envelopes are only available for the %s format", FormatJSON)
+	}
+	e := yaml.NewEncoder(w)
+	switch format {
+	case FormatJSON:
+		je := json.NewEncoder(w)
+		je.SetIndent("", "  ")
+		if opts.Envelope {
+			return func(r Registry) error {
+				doc := envelope{Source: r.Source, FileDate: r.FileDate, Count: len(r.Entries), Entries: r.Entries}
+				if opts.CompactDates {
+					doc.FileDate, doc.Entries = epochDate(r.FileDate), compactEntries(r.Entries)
+				}
+				return je.Encode(doc)
+			}, nil
+		}
+		return func(r Registry) error { return je.Encode(document(r, opts)) }, nil
+	case FormatYAML:
+		return func(r Registry) error { return e.Encode(document(r, opts)) }, nil
+	case FormatByType:
+		return func(r Registry) error { return e.Encode(byTypeDocument(r, opts)) }, nil
+	case FormatText:
+		return func(r Registry) error { return writeText(w, r, opts.descriptionJoin(), opts.Color) }, nil
+	case FormatGrep:
+		return func(r Registry) error { return writeGrep(w, r, opts.Color) }, nil
+	case FormatGoMap:
+		return func(r Registry) error { return writeGoMap(w, r) }, nil
+	case FormatEnv:
+		return func(r Registry) error { return writeEnv(w, r) }, nil
+	case FormatCSV:
+		return func(r Registry) error { return writeCSV(w, r, opts.descriptionJoin()) }, nil
+	case FormatScriptLangs:
+		return func(r Registry) error { return e.Encode(scriptLanguages(r)) }, nil
+	case FormatRegistry:
+		return func(r Registry) error { return r.WriteRegistryWith(w, opts) }, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// byTypeDocument maps entry types to their entries, keyed by Subtag,
+// or by Tag for grandfathered and redundant entries, as returned by entryDocument.
+func byTypeDocument(r Registry, opts Options) map[Type]map[string]any {
+	doc := make(map[Type]map[string]any)
+	for _, e := range r.Entries {
+		if doc[e.Type] == nil {
+			doc[e.Type] = make(map[string]any)
+		}
+		doc[e.Type][e.Key()] = entryDocument(e, opts)
+	}
+	return doc
+}
+
+// envKey returns the variable name for a language subtag or tag in the env format,
+// like "LANG_ZH_HANS" for "zh-Hans".
+func envKey(key string) string {
+	return "LANG_" + strings.Map(func(c rune) rune {
+		if isAlnum(string(c)) {
+			return unicode.ToUpper(c)
+		}
+		return '_'
+	}, key)
+}
+
+// envEscaper escapes the characters special within double quotes for shells and .env parsers.
+var envEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+
+// envValue returns a description as a value in the env format, only quoted if needed.
+func envValue(s string) string {
+	if s != "" && strings.IndexFunc(s, func(c rune) bool { return !isAlnum(string(c)) && !strings.ContainsRune("_-.,/", c) }) == -1 {
+		return s
+	}
+	return `"` + envEscaper.Replace(s) + `"`
+}
+
+// writeEnv writes NAME=value lines mapping language subtags, and the grandfathered and redundant tags,
+// to their first description, sorted by name, like LANG_DE=German. Ranges like "qaa..qtz" are skipped.
+func writeEnv(w io.Writer, r Registry) error {
+	names := make(map[string]string)
+	for _, e := range r.Entries {
+		isLanguage := e.Type == TypeLanguage || e.Type == TypeGrandfathered || e.Type == TypeRedundant
+		if !isLanguage || len(e.Description) == 0 || strings.Contains(e.Key(), "..") {
+			continue
+		}
+		names[envKey(e.Key())] = e.Description[0]
+	}
+	keys := make([]string, 0, len(names))
+	for k := range names {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	bw := bufio.NewWriter(w)
+	for _, k := range keys {
+		fmt.Fprintf(bw, "%s=%s\n", k, envValue(names[k]))
+	}
+	return bw.Flush()
+}
+
+// scriptLanguages maps Suppress-Script codes to the language subtags suppressing them,
+// in registry order, as the inverse of the Suppress-Script field.
+func scriptLanguages(r Registry) map[string][]string {
+	langs := make(map[string][]string)
+	for _, e := range r.Entries {
+		if e.Type == TypeLanguage && !e.SuppressScript.IsZero() {
+			s := e.SuppressScript.String()
+			langs[s] = append(langs[s], e.Subtag)
+		}
+	}
+	return langs
+}
+
+// writeGoMap writes Go source declaring a Languages map of language subtags
+// to their first description, sorted by subtag, suitable for go:generate.
+func writeGoMap(w io.Writer, r Registry) error {
+	languages := make(map[string]string)
+	for _, e := range r.Entries {
+		if e.Type == TypeLanguage && len(e.Description) > 0 {
+			languages[e.Subtag] = e.Description[0]
+		}
+	}
+	subtags := make([]string, 0, len(languages))
+	for subtag := range languages {
+		subtags = append(subtags, subtag)
+	}
+	sort.Strings(subtags)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated from the IANA language subtag registry; DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package languages\n\n")
+	fmt.Fprintf(buf, "// Languages maps language subtags to their description, as of File-Date %s.\n",
+		time.Time(r.FileDate).Format("2006-01-02"))
+	fmt.Fprintf(buf, "var Languages = map[string]string{\n")
+	for _, subtag := range subtags {
+		fmt.Fprintf(buf, "%s: %s,\n", strconv.Quote(subtag), strconv.Quote(languages[subtag]))
+	}
+	fmt.Fprintf(buf, "}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated invalid Go source: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// ANSI escape sequences used by the text and grep formats with Options.Color.
+const (
+	ansiDim   = "\x1b[2m"
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
+
+// colorize wraps s in the ANSI escape sequence seq, if color is set and seq is not empty.
+func colorize(color bool, seq, s string) string {
+	if !color || seq == "" {
+		return s
+	}
+	return seq + s + ansiReset
+}
+
+// entryColor returns the ANSI escape sequence for the key and descriptions of an entry:
+// red for deprecated entries, none otherwise.
+func entryColor(e Entry) string {
+	if e.Deprecated.IsZero() {
+		return ""
+	}
+	return ansiRed
+}
+
+// writeText writes one "type subtag: descriptions" line per entry, the descriptions being joined by sep.
+// With color, types are dimmed and deprecated entries are red.
+func writeText(w io.Writer, r Registry, sep string, color bool) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range r.Entries {
+		rest := fmt.Sprintf("%s: %s", e.Key(), strings.Join(e.Description, sep))
+		fmt.Fprintf(bw, "%s %s\n", colorize(color, ansiDim, string(e.Type)), colorize(color, entryColor(e), rest))
+	}
+	return bw.Flush()
+}
+
+// grepEscaper escapes backslashes, tabs, and line breaks, to keep grep format fields on one line.
+var grepEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// writeGrep writes one "subtag<TAB>type<TAB>description" line per description of each entry,
+// or a single line with an empty description for entries without one, for grep and awk.
+// With color, colored like the text format.
+func writeGrep(w io.Writer, r Registry, color bool) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range r.Entries {
+		descriptions := e.Description
+		if len(descriptions) == 0 {
+			descriptions = []string{""}
+		}
+		seq := entryColor(e)
+		key := colorize(color, seq, grepEscaper.Replace(e.Key()))
+		typ := colorize(color, ansiDim, grepEscaper.Replace(string(e.Type)))
+		for _, d := range descriptions {
+			fmt.Fprintf(bw, "%s\t%s\t%s\n", key, typ, colorize(color, seq, grepEscaper.Replace(d)))
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteDeprecationCSV writes an old,new,date_deprecated CSV row for each deprecated entry,
+// in registry order, with an empty new column for entries lacking a Preferred-Value.
+func WriteDeprecationCSV(w io.Writer, r Registry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"old", "new", "date_deprecated"})
+	for _, e := range r.Entries {
+		if e.Deprecated.IsZero() {
+			continue
+		}
+		cw.Write([]string{e.Key(), e.PreferredValue, formatDate(e.Deprecated)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvHeader names the columns of the csv format.
+var csvHeader = []string{"type", "subtag", "description", "added", "deprecated", "preferred_value",
+	"prefix", "scope", "macrolanguage", "suppress_script", "comments"}
+
+// writeCSV writes a row for each entry, in registry order, with the columns of csvHeader,
+// the subtag column holding the Tag of grandfathered and redundant entries.
+// Multiple descriptions are joined by join, and multiple prefixes by ";", in registry order.
+func writeCSV(w io.Writer, r Registry, join string) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, e := range r.Entries {
+		cw.Write([]string{string(e.Type), e.Key(), strings.Join(e.Description, join),
+			formatDate(e.Added), formatDate(e.Deprecated), e.PreferredValue, strings.Join(e.Prefix, ";"),
+			string(e.Scope), e.MacroLanguage, e.SuppressScript.String(), e.Comments})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// StreamYAML parses the registry in r block by block, writing each entry to w as soon as
+// it is parsed, producing the same document as the yaml format without holding all entries.
+// Like for the yaml format, opts.CompactDates writes dates as EpochDays.
+//
+// Parsing uses opts like ParseWith: it fails past opts.MaxEntries, keeps line breaks with
+// opts.Faithful, and unless opts.FailFast, skips malformed blocks, returning their errors once done.
+func StreamYAML(w io.Writer, r io.Reader, opts Options) error {
+	var entries int
+	err := streamEntries(r, opts, func(fd Date) error {
+		header, err := yaml.Marshal(document(Registry{FileDate: fd}, opts))
+		if err != nil {
+			return err
+		}
+		// Entries are appended below the header key as they arrive.
+		_, err = w.Write(bytes.TrimSuffix(header, []byte(" []\n")))
+		return err
+	}, func(e Entry) error {
+		// A single-item sequence, indented like the entries of the full document.
+		item, err := yaml.Marshal([]any{entryDocument(e, opts)})
+		if err != nil {
+			return err
+		}
+		if entries == 0 {
+			if _, err = io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		entries++
+		for _, line := range bytes.SplitAfter(item, []byte("\n")) {
+			if len(line) == 0 {
+				continue
+			}
+			if _, err = w.Write(append([]byte("    "), line...)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil && entries == 0 {
+		_, err = io.WriteString(w, " []\n")
+	}
+	return err
+}
+
+// ToYAML serializes a single entry to YAML, like in the entries list of the YAML format.
+func (e Entry) ToYAML() ([]byte, error) {
+	return yaml.Marshal(e)
+}
+
+// ToJSON serializes a single entry to JSON, omitting empty fields like ToYAML.
+func (e Entry) ToJSON() ([]byte, error) {
+	return json.Marshal(e)
+}
@@ -0,0 +1,632 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// testRegistryHead is the file-date block of a registry without entries.
+const testRegistryHead = "File-Date: 2023-08-02\n"
+
+// encode encodes the registry in the given format, failing the test on errors.
+func encode(t *testing.T, r Registry, format string, opts Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	enc, err := NewEncoder(&buf, format, opts)
+	if err != nil {
+		t.Fatalf("NewEncoder(%q) failed: %v", format, err)
+	}
+	if err = enc(r); err != nil {
+		t.Fatalf("encoding %s failed: %v", format, err)
+	}
+	return buf.String()
+}
+
+func TestNewEncoder_byType(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	var doc map[Type]map[string]Entry
+	if err := yaml.Unmarshal([]byte(encode(t, *r, FormatByType, Options{})), &doc); err != nil {
+		t.Fatalf("failed decoding bytype output: %v", err)
+	}
+	if len(doc) != 7 {
+		t.Errorf("got %d types, want 7", len(doc))
+	}
+	tests := []struct {
+		typ         Type
+		key         string
+		description string
+	}{
+		{TypeLanguage, "de", "German"},
+		{TypeExtlang, "cmn", "Mandarin Chinese"},
+		{TypeLanguage, "cmn", "Mandarin Chinese"}, // Subtags are only unique within a type.
+		{TypeScript, "Latn", "Latin"},
+		{TypeRegion, "419", "Latin America and the Caribbean"},
+		{TypeVariant, "1901", "Traditional German orthography"},
+		{TypeGrandfathered, "i-klingon", "Klingon"}, // Tags key grandfathered and redundant entries.
+		{TypeRedundant, "zh-Hant", "traditional Chinese"},
+	}
+	for _, test := range tests {
+		t.Run(string(test.typ)+"/"+test.key, func(t *testing.T) {
+			e, ok := doc[test.typ][test.key]
+			if !ok {
+				t.Fatalf("missing %s %s", test.typ, test.key)
+			}
+			if e.Key() != test.key || e.Type != test.typ || len(e.Description) == 0 || e.Description[0] != test.description {
+				t.Errorf("%s %s is %+v, want description %q", test.typ, test.key, e, test.description)
+			}
+		})
+	}
+}
+
+func TestEntry_ToYAML_ToJSON(t *testing.T) {
+	de := Entry{Type: TypeLanguage, Subtag: "de", Description: []string{"German"}, Added: mustDate("2005-10-16"),
+		SuppressScript: Script{'L', 'a', 't', 'n'}}
+	iw := Entry{Type: TypeLanguage, Subtag: "iw", Description: []string{"Hebrew"}, Added: mustDate("2005-10-16"),
+		Deprecated: mustDate("1989-01-01"), PreferredValue: "he"}
+	tests := []struct {
+		name   string
+		encode func() ([]byte, error)
+		want   string
+	}{
+		{"yaml", de.ToYAML, `added: "2005-10-16"
+description:
+    - German
+subtag: de
+suppress-script: Latn
+type: language
+`},
+		{"json", de.ToJSON, `{"added":"2005-10-16","description":["German"],"subtag":"de","suppress-script":"Latn","type":"language"}`},
+		{"yaml deprecated", iw.ToYAML, `added: "2005-10-16"
+deprecated: "1989-01-01"
+description:
+    - Hebrew
+preferred-value: he
+subtag: iw
+type: language
+`},
+		{"json deprecated", iw.ToJSON, `{"added":"2005-10-16","deprecated":"1989-01-01","description":["Hebrew"],"preferred-value":"he","subtag":"iw","type":"language"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.encode()
+			if err != nil || string(got) != test.want {
+				t.Errorf("got %s, %v, want %s", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestNewEncoder_goMap(t *testing.T) {
+	r := Registry{FileDate: mustDate("2023-08-02"), Entries: []Entry{
+		{Type: TypeLanguage, Subtag: "vo", Description: []string{"Volapük"}},
+		{Type: TypeLanguage, Subtag: "de", Description: []string{"German", "Deutsch"}},
+		{Type: TypeLanguage, Subtag: "zz", Description: []string{`A "quoted" \ name`}},
+		{Type: TypeRegion, Subtag: "DE", Description: []string{"Germany"}},
+	}}
+	src := encode(t, r, FormatGoMap, Options{})
+	f, err := parser.ParseFile(token.NewFileSet(), "languages.go", src, 0)
+	if err != nil {
+		t.Fatalf("generated invalid Go source: %v\n%s", err, src)
+	}
+	if f.Name.Name != "languages" {
+		t.Errorf("package is %s, want languages", f.Name.Name)
+	}
+	// Decode the map literal of the Languages declaration.
+	got := make(map[string]string)
+	var order []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		kv, ok := n.(*ast.KeyValueExpr)
+		if !ok {
+			return true
+		}
+		k, kerr := strconv.Unquote(kv.Key.(*ast.BasicLit).Value)
+		v, verr := strconv.Unquote(kv.Value.(*ast.BasicLit).Value)
+		if kerr != nil || verr != nil {
+			t.Errorf("invalid literals in %s: %v", src, errors.Join(kerr, verr))
+		}
+		got[k] = v
+		order = append(order, k)
+		return false
+	})
+	tests := []struct {
+		subtag string
+		want   string
+	}{
+		{"de", "German"}, // The first description only.
+		{"vo", "Volapük"},
+		{"zz", `A "quoted" \ name`},
+	}
+	for _, test := range tests {
+		t.Run(test.subtag, func(t *testing.T) {
+			if got[test.subtag] != test.want {
+				t.Errorf("Languages[%q] = %q, want %q", test.subtag, got[test.subtag], test.want)
+			}
+		})
+	}
+	if want := []string{"de", "vo", "zz"}; !slices.Equal(order, want) {
+		t.Errorf("Languages has the subtags %q, want %q in that order", order, want)
+	}
+}
+
+func TestNewEncoder_csv(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	text := encode(t, *r, FormatCSV, Options{})
+	if again := encode(t, *r, FormatCSV, Options{}); again != text {
+		t.Errorf("csv output is not stable across runs")
+	}
+	rows, err := csv.NewReader(strings.NewReader(text)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != len(r.Entries)+1 || !slices.Equal(rows[0], csvHeader) {
+		t.Fatalf("got %d rows starting with %q, want %d starting with the header", len(rows), rows[0], len(r.Entries)+1)
+	}
+	tests := []struct {
+		row  int
+		want []string
+	}{
+		{1, []string{"language", "de", "German", "2005-10-16", "", "", "", "", "", "Latn", ""}},
+		{9, []string{"language", "mo", "Moldavian; Moldovan", "2005-10-16", "2008-11-22", "ro", "", "", "", "Latn", ""}},
+		{24, []string{"extlang", "cmn", "Mandarin Chinese", "2009-07-29", "", "cmn", "zh", "", "zh", "", ""}},
+		{39, []string{"variant", "1694acad", "Early Modern French", "2007-03-20", "", "", "fr", "", "", "",
+			`17th century French, as catalogued in the "Dictionnaire de l'académie françoise", 4eme ed. 1694; ` +
+				"frequently includes elements of Middle French, as this is a transitional period"}},
+		{43, []string{"grandfathered", "art-lojban", "Lojban", "2001-11-11", "2003-09-02", "jbo", "", "", "", "", ""}},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.want[:2], " "), func(t *testing.T) {
+			if got := rows[test.row]; !slices.Equal(got, test.want) {
+				t.Errorf("row %d = %q, want %q", test.row, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewEncoder_scriptLangs(t *testing.T) {
+	var doc map[string][]string
+	if err := yaml.Unmarshal([]byte(encode(t, *parseTestdata(t, Options{}), FormatScriptLangs, Options{})), &doc); err != nil {
+		t.Fatalf("failed decoding script-langs output: %v", err)
+	}
+	tests := []struct {
+		script string
+		want   []string
+	}{
+		{"Latn", []string{"de", "en", "fr", "id", "in", "mo", "ro"}},
+		{"Hebr", []string{"he", "iw"}},
+		{"Hans", nil}, // No language suppresses it.
+	}
+	for _, test := range tests {
+		t.Run(test.script, func(t *testing.T) {
+			if got := doc[test.script]; !slices.Equal(got, test.want) {
+				t.Errorf("script-langs[%q] = %q, want %q", test.script, got, test.want)
+			}
+		})
+	}
+	if len(doc) != 2 {
+		t.Errorf("script-langs has %d scripts, want 2: %v", len(doc), doc)
+	}
+}
+
+func TestWriteDeprecationCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDeprecationCSV(&buf, *parseTestdata(t, Options{})); err != nil {
+		t.Fatalf("WriteDeprecationCSV() failed: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	want := [][]string{
+		{"old", "new", "date_deprecated"},
+		{"in", "id", "1989-01-01"},
+		{"iw", "he", "1989-01-01"},
+		{"mo", "ro", "2008-11-22"},
+		{"BU", "MM", "1989-12-05"},
+		{"art-lojban", "jbo", "2003-09-02"},
+		{"i-klingon", "tlh", "2004-02-24"},
+		{"zh-guoyu", "cmn", "2005-07-15"},
+		{"zh-cmn", "cmn", "2009-07-29"},
+		{"zh-cmn-Hans", "cmn-Hans", "2009-07-29"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %q", len(rows), len(want), rows)
+	}
+	for i, row := range rows {
+		t.Run(want[i][0], func(t *testing.T) {
+			if !slices.Equal(row, want[i]) {
+				t.Errorf("row %d = %q, want %q", i, row, want[i])
+			}
+		})
+	}
+}
+
+func TestNewEncoder_compactDates(t *testing.T) {
+	r := Registry{FileDate: mustDate("2023-08-02"), Entries: []Entry{
+		{Type: TypeLanguage, Subtag: "iw", Description: []string{"Hebrew"}, Added: mustDate("2005-10-16"),
+			Deprecated: mustDate("1989-01-01"), PreferredValue: "he"},
+		{Type: TypeLanguage, Subtag: "he", Description: []string{"Hebrew"}, Added: mustDate("2005-10-16")},
+	}}
+	tests := []struct {
+		format   string
+		opts     Options
+		want     []string
+		unwanted string
+		decode   func([]byte, any) error
+	}{
+		{FormatYAML, Options{CompactDates: true}, []string{"file-date: 19571\n", "added: 13072\n", "deprecated: 6940\n"}, "2005", yaml.Unmarshal},
+		{FormatJSON, Options{CompactDates: true}, []string{`"file-date": 19571`, `"added": 13072`, `"deprecated": 6940`}, "2005", json.Unmarshal},
+		{FormatJSON, Options{CompactDates: true, Envelope: true}, []string{`"fileDate": 19571`, `"added": 13072`}, "2005", nil},
+		{FormatByType, Options{CompactDates: true}, []string{"added: 13072\n", "deprecated: 6940\n"}, "2005", nil},
+		{FormatYAML, Options{}, []string{`file-date: "2023-08-02"`, `added: "2005-10-16"`}, "13072", yaml.Unmarshal},
+	}
+	for _, test := range tests {
+		name := test.format
+		if test.opts.Envelope {
+			name += " envelope"
+		}
+		if !test.opts.CompactDates {
+			name += " not compact"
+		}
+		t.Run(name, func(t *testing.T) {
+			got := encode(t, r, test.format, test.opts)
+			for _, want := range test.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("output does not contain %q:\n%s", want, got)
+				}
+			}
+			if strings.Contains(got, test.unwanted) {
+				t.Errorf("output contains %q:\n%s", test.unwanted, got)
+			}
+			// The "deprecated" key is only written once, for iw.
+			if n := strings.Count(got, "deprecated"); n != 1 {
+				t.Errorf("output has %d deprecated keys, want 1:\n%s", n, got)
+			}
+			if test.decode == nil {
+				return
+			}
+			var back Registry
+			if err := test.decode([]byte(got), &back); err != nil {
+				t.Fatalf("failed decoding output: %v", err)
+			}
+			if !back.FileDate.Equal(r.FileDate) || len(back.Entries) != len(r.Entries) {
+				t.Fatalf("decoded %+v, want %+v", back, r)
+			}
+			for i, e := range back.Entries {
+				if want := r.Entries[i]; !e.Added.Equal(want.Added) || !e.Deprecated.Equal(want.Deprecated) || e.Key() != want.Key() {
+					t.Errorf("decoded entry %d = %+v, want %+v", i, e, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_marshalKeys(t *testing.T) {
+	r := mustParse(t, testRegistryHead+"%%\nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n")
+	tests := []struct {
+		name      string
+		marshal   func(any) ([]byte, error)
+		unmarshal func([]byte, any) error
+		value     any
+	}{
+		{"yaml value", yaml.Marshal, yaml.Unmarshal, *r},
+		{"yaml pointer", yaml.Marshal, yaml.Unmarshal, r},
+		{"json value", json.Marshal, json.Unmarshal, *r},
+		{"json pointer", json.Marshal, json.Unmarshal, r},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bs, err := test.marshal(test.value)
+			if err != nil {
+				t.Fatalf("marshalling failed: %v", err)
+			}
+			var doc map[string]any
+			if err = test.unmarshal(bs, &doc); err != nil {
+				t.Fatalf("failed decoding %s: %v", bs, err)
+			}
+			if got := slices.Sorted(maps.Keys(doc)); !slices.Equal(got, []string{"entries", "file-date"}) {
+				t.Errorf("output has keys %q, want entries and file-date", got)
+			}
+			if got := doc["file-date"]; got != "2023-08-02" {
+				t.Errorf("file-date = %#v, want the date-only string", got)
+			}
+			if got, ok := doc["entries"].([]any); !ok || len(got) != 1 {
+				t.Errorf("entries = %#v, want 1 entry", doc["entries"])
+			}
+		})
+	}
+}
+
+func TestStreamYAML(t *testing.T) {
+	text, err := os.ReadFile(testdataRegistry)
+	if err != nil {
+		t.Fatalf("failed reading test registry: %v", err)
+	}
+	tests := []struct {
+		name string
+		text string
+		opts Options
+	}{
+		{"testdata", string(text), Options{}},
+		{"compact dates", string(text), Options{CompactDates: true}},
+		{"faithful", string(text), Options{Faithful: true}},
+		{"single entry", testRegistryHead + "%%\nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n", Options{}},
+		{"no entries", testRegistryHead, Options{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := ParseWith(strings.NewReader(test.text), test.opts)
+			if err != nil {
+				t.Fatalf("failed parsing test registry: %v", err)
+			}
+			want := encode(t, *r, FormatYAML, test.opts)
+			var buf bytes.Buffer
+			if err = StreamYAML(&buf, strings.NewReader(test.text), test.opts); err != nil {
+				t.Fatalf("StreamYAML() failed: %v", err)
+			}
+			if got := buf.String(); got != want {
+				t.Errorf("StreamYAML() wrote:\n%s\nwant the batch output:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestNewEncoder_envelope(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"downloaded", "https://mirror.example.com/language-subtag-registry"},
+		{"unknown", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r.Source = test.source
+			var got struct {
+				Source   string  `json:"source"`
+				FileDate Date    `json:"fileDate"`
+				Count    int     `json:"count"`
+				Entries  []Entry `json:"entries"`
+			}
+			if err := json.Unmarshal([]byte(encode(t, *r, FormatJSON, Options{Envelope: true})), &got); err != nil {
+				t.Fatalf("failed decoding envelope: %v", err)
+			}
+			if got.Source != test.source || got.FileDate.String() != "2023-08-02" || got.Count != len(r.Entries) {
+				t.Errorf("envelope has source %q, fileDate %s, count %d, want %q, 2023-08-02, %d",
+					got.Source, got.FileDate, got.Count, test.source, len(r.Entries))
+			}
+			if !slices.Equal(keys(got.Entries), keys(r.Entries)) {
+				t.Errorf("envelope has entries %q, want %q", keys(got.Entries), keys(r.Entries))
+			}
+		})
+	}
+	if _, err := NewEncoder(io.Discard, FormatYAML, Options{Envelope: true}); err == nil {
+		t.Errorf("NewEncoder(%s) with an envelope succeeded, want an error", FormatYAML)
+	}
+}
+
+func TestNewEncoder_color(t *testing.T) {
+	r := Registry{Entries: []Entry{
+		{Type: TypeLanguage, Subtag: "iw", Description: []string{"Hebrew"}, Deprecated: mustDate("1989-01-01")},
+		{Type: TypeRegion, Subtag: "DE", Description: []string{"Germany"}},
+	}}
+	tests := []struct {
+		format string
+		color  bool
+		want   []string // Sequences in the output, or none of ANSI if empty.
+	}{
+		{FormatText, false, nil},
+		{FormatGrep, false, nil},
+		{FormatText, true, []string{ansiDim, ansiRed}},
+		{FormatGrep, true, []string{ansiRed}},
+	}
+	for _, test := range tests {
+		t.Run(test.format+"/"+strconv.FormatBool(test.color), func(t *testing.T) {
+			got := encode(t, r, test.format, Options{Color: test.color})
+			if len(test.want) == 0 && strings.Contains(got, "\x1b[") {
+				t.Errorf("output has ANSI escape sequences: %q", got)
+			}
+			for _, seq := range test.want {
+				if !strings.Contains(got, seq) {
+					t.Errorf("output %q does not contain %q", got, seq)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"de", "LANG_DE"},
+		{"zh-Hans", "LANG_ZH_HANS"},
+		{"i-klingon", "LANG_I_KLINGON"},
+		{"zh-cmn-Hans", "LANG_ZH_CMN_HANS"},
+		{"x.y", "LANG_X_Y"},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			if got := envKey(test.key); got != test.want {
+				t.Errorf("envKey(%q) = %q, want %q", test.key, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewEncoder_env(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	got := encode(t, *r, FormatEnv, Options{})
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"LANG_DE=German\n", true},
+		{"LANG_ZH_HANS=\"simplified Chinese\"\n", true},
+		{"LANG_I_KLINGON=Klingon\n", true},
+		{"LANG_QAA", false},  // Ranges are skipped.
+		{"LANG_LATN", false}, // Scripts are not languages.
+		{"LANG_DE=Germany", false},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			if strings.Contains(got, test.line) != test.want {
+				t.Errorf("env output contains %q: %t, want %t\n%s", test.line, !test.want, test.want, got)
+			}
+		})
+	}
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if !slices.IsSorted(lines) {
+		t.Errorf("env output is not sorted:\n%s", got)
+	}
+}
+
+func TestNewEncoder_text(t *testing.T) {
+	r := Registry{Entries: []Entry{
+		{Type: TypeLanguage, Subtag: "ro", Description: []string{"Romanian", "Moldavian", "Moldovan"}},
+		{Type: TypeRegion, Subtag: "DE", Description: []string{"Germany"}},
+	}}
+	tests := []struct {
+		name string
+		join string
+		want string
+	}{
+		{"default", "", "language ro: Romanian; Moldavian; Moldovan\nregion DE: Germany\n"},
+		{"slash", " / ", "language ro: Romanian / Moldavian / Moldovan\nregion DE: Germany\n"},
+		{"newline", "\n", "language ro: Romanian\nMoldavian\nMoldovan\nregion DE: Germany\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := encode(t, r, FormatText, Options{DescriptionJoin: test.join}); got != test.want {
+				t.Errorf("text output is %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewEncoder_csvDescriptionJoin(t *testing.T) {
+	r := Registry{Entries: []Entry{
+		{Type: TypeLanguage, Subtag: "ro", Description: []string{"Romanian", "Moldavian", "Moldovan"}},
+		{Type: TypeVariant, Subtag: "1901", Description: []string{"Traditional German orthography"}, Prefix: []string{"de", "gsw"}},
+	}}
+	tests := []struct {
+		name string
+		join string
+		want [2]string // The description of each entry.
+	}{
+		{"default", "", [2]string{"Romanian; Moldavian; Moldovan", "Traditional German orthography"}},
+		{"slash", " / ", [2]string{"Romanian / Moldavian / Moldovan", "Traditional German orthography"}},
+		{"newline", "\n", [2]string{"Romanian\nMoldavian\nMoldovan", "Traditional German orthography"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := csv.NewReader(strings.NewReader(encode(t, r, FormatCSV, Options{DescriptionJoin: test.join}))).ReadAll()
+			if err != nil || len(rows) != 3 {
+				t.Fatalf("csv output has rows %q, %v, want 3", rows, err)
+			}
+			if got := [2]string{rows[1][2], rows[2][2]}; got != test.want {
+				t.Errorf("csv descriptions are %q, want %q", got, test.want)
+			}
+			// Prefixes keep their own separator.
+			if got := rows[2][6]; got != "de;gsw" {
+				t.Errorf("csv prefixes are %q, want %q", got, "de;gsw")
+			}
+		})
+	}
+}
+
+func TestNewEncoder_grep(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		want  [][]string
+	}{
+		{"one line per description", Entry{Type: TypeLanguage, Subtag: "ro", Description: []string{"Romanian", "Moldavian"}},
+			[][]string{{"ro", "language", "Romanian"}, {"ro", "language", "Moldavian"}}},
+		{"tag", Entry{Type: TypeRedundant, Tag: "zh-Hans", Description: []string{"simplified Chinese"}},
+			[][]string{{"zh-Hans", "redundant", "simplified Chinese"}}},
+		{"escaped", Entry{Type: TypeLanguage, Subtag: "xx", Description: []string{"Tab\there", "Line\nbreak", `Back\slash`}},
+			[][]string{{"xx", "language", `Tab\there`}, {"xx", "language", `Line\nbreak`}, {"xx", "language", `Back\\slash`}}},
+		{"no description", Entry{Type: TypeLanguage, Subtag: "xx"}, [][]string{{"xx", "language", ""}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := encode(t, Registry{Entries: []Entry{test.entry}}, FormatGrep, Options{})
+			lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+			if len(lines) != len(test.want) {
+				t.Fatalf("grep output is %q, want %d lines", out, len(test.want))
+			}
+			for i, line := range lines {
+				if fields := strings.Split(line, "\t"); !slices.Equal(fields, test.want[i]) {
+					t.Errorf("line %d has fields %q, want %q", i, fields, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewEncoder_roundTrip(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		format string
+		decode func([]byte, any) error
+	}{
+		{FormatJSON, json.Unmarshal},
+		{FormatYAML, yaml.Unmarshal},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var got Registry
+			if err := test.decode([]byte(encode(t, *r, test.format, Options{})), &got); err != nil {
+				t.Fatalf("failed decoding %s: %v", test.format, err)
+			}
+			if !reflect.DeepEqual(got.Entries, r.Entries) || !got.FileDate.Equal(r.FileDate) {
+				t.Errorf("decoded %s differs from the parsed registry", test.format)
+			}
+		})
+	}
+}
+
+func TestDate_Script_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    string
+		wantErr bool
+	}{
+		{"date", `{"added": "2005-10-16", "suppress-script": "Latn"}`, "2005-10-16 Latn", false},
+		{"epoch days", `{"added": 13072}`, "2005-10-16 ", false},
+		{"invalid date", `{"added": "2005-13-16"}`, "", true},
+		{"date not a string", `{"added": true}`, "", true},
+		{"short script", `{"suppress-script": "Lat"}`, "", true},
+		{"script not a string", `{"suppress-script": 1234}`, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var e Entry
+			err := json.Unmarshal([]byte(test.json), &e)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Unmarshal(%s) = %v, want error %t", test.json, err, test.wantErr)
+			}
+			if got := e.Added.String() + " " + e.SuppressScript.String(); err == nil && got != test.want {
+				t.Errorf("Unmarshal(%s) gave %q, want %q", test.json, got, test.want)
+			}
+		})
+	}
+}
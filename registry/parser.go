@@ -0,0 +1,611 @@
+// Package registry parses the IANA Language Subtag Registry defined by RFC 5646,
+// and queries, validates, and serializes the parsed entries.
+//
+// It performs no network I/O: Parse and StreamEntries read a registry from any io.Reader,
+// like a downloaded copy of the file published at Url.
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Url is the location of the registry published by IANA.
+const Url = "https://www.iana.org/assignments/language-subtag-registry/language-subtag-registry"
+
+// PropRowRx matches "Key: value" rows, tolerating a missing space after the colon, as in hand-edited files,
+// and an empty value, continued on the next lines. Fields left empty, like "Subtag:", fail parsing.
+var PropRowRx = regexp.MustCompile(`^((?:-|[[:alpha:]])+): *(.*)$`)
+
+type Date time.Time
+
+// DateFromEpochDays is the inverse of Date.EpochDays.
+func DateFromEpochDays(days int64) Date {
+	return Date(time.Unix(days*secondsPerDay, 0).UTC())
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+// EpochDays returns the number of days since the Unix epoch, 1970-01-01, to d.
+func (d Date) EpochDays() int64 {
+	return time.Time(d).Unix() / secondsPerDay
+}
+
+func (d Date) IsZero() bool {
+	t := time.Time(d)
+	return t.IsZero()
+}
+
+// Equal reports whether d and o are the same date.
+func (d Date) Equal(o Date) bool {
+	return time.Time(d).Equal(time.Time(o))
+}
+
+// Set implements flag.Value, parsing date-only values like "2009-07-29".
+func (d *Date) Set(s string) error {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return err
+	}
+	*d = Date(t)
+	return nil
+}
+
+// String implements flag.Value and fmt.Stringer, formatting non-zero dates like the registry.
+func (d Date) String() string {
+	return formatDate(d)
+}
+
+// MarshalJSON implements json.Marshaler, using the same format as YAML.
+func (d Date) MarshalJSON() ([]byte, error) {
+	v, _ := d.MarshalYAML()
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting the formats UnmarshalYAML accepts.
+func (d *Date) UnmarshalJSON(bs []byte) error {
+	var days int64
+	if err := json.Unmarshal(bs, &days); err == nil {
+		*d = DateFromEpochDays(days)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(bs, &s); err != nil {
+		return err
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return err
+	}
+	*d = Date(t)
+	return nil
+}
+
+func (d Date) MarshalYAML() (any, error) {
+	s := time.Time(d).Format("2006-01-02")
+	return s, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, accepting both date-only strings and EpochDays,
+// so that outputs written with Options.CompactDates decode too.
+func (d *Date) UnmarshalYAML(value *yaml.Node) error {
+	if value.Tag == "!!int" {
+		var days int64
+		if err := value.Decode(&days); err != nil {
+			return err
+		}
+		*d = DateFromEpochDays(days)
+		return nil
+	}
+	t, err := time.Parse("2006-01-02", value.Value)
+	if err != nil {
+		return err
+	}
+	*d = Date(t)
+	return nil
+}
+
+type Script [4]rune
+
+// IsZero implements yaml.IsZeroer to support omitempty in yaml encoding.
+func (s Script) IsZero() bool {
+	var zero Script
+	return s == zero
+}
+
+// String implements fmt.Stringer, returning the script code like "Latn", or "" for the zero Script.
+func (s Script) String() string {
+	if s.IsZero() {
+		return ""
+	}
+	return string(s[:])
+}
+
+// MarshalJSON implements json.Marshaler, using the same string format as YAML.
+func (s Script) MarshalJSON() ([]byte, error) {
+	v, _ := s.MarshalYAML()
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, using the same string format as YAML.
+func (s *Script) UnmarshalJSON(bs []byte) error {
+	var v string
+	if err := json.Unmarshal(bs, &v); err != nil {
+		return err
+	}
+	return s.UnmarshalYAML(&yaml.Node{Kind: yaml.ScalarNode, Value: v})
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (s Script) MarshalYAML() (any, error) {
+	bs := make([]byte, 4)
+	for i := 0; i < 4; i++ {
+		bs[i] = byte(s[i])
+	}
+	return string(bs), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (s *Script) UnmarshalYAML(value *yaml.Node) error {
+	if len(value.Value) != 4 {
+		return fmt.Errorf("script %q does not have length 4", value.Value)
+	}
+	for i := 0; i < 4; i++ {
+		s[i] = rune(value.Value[i])
+	}
+	return nil
+}
+
+// Type is the type of a registry entry, per RFC 5646 §3.1.3.
+type Type string
+
+// Entry types, in registry order.
+const (
+	TypeLanguage      Type = "language"
+	TypeExtlang       Type = "extlang"
+	TypeScript        Type = "script"
+	TypeRegion        Type = "region"
+	TypeVariant       Type = "variant"
+	TypeGrandfathered Type = "grandfathered"
+	TypeRedundant     Type = "redundant"
+)
+
+// String implements fmt.Stringer.
+func (t Type) String() string {
+	return string(t)
+}
+
+// IsValid reports whether t is one of the entry types defined by RFC 5646.
+func (t Type) IsValid() bool {
+	_, ok := typeOrder[t]
+	return ok
+}
+
+// Scope is the scope of a language or extlang entry, per RFC 5646 §3.1.11.
+// The zero value means the entry has no Scope, like most entries.
+type Scope string
+
+// Entry scopes.
+const (
+	ScopeMacrolanguage Scope = "macrolanguage"
+	ScopeCollection    Scope = "collection"
+	ScopeSpecial       Scope = "special"
+	ScopePrivateUse    Scope = "private-use"
+)
+
+// String implements fmt.Stringer.
+func (s Scope) String() string {
+	return string(s)
+}
+
+// IsValid reports whether s is one of the scopes defined by RFC 5646.
+// The zero value is not.
+func (s Scope) IsValid() bool {
+	switch s {
+	case ScopeMacrolanguage, ScopeCollection, ScopeSpecial, ScopePrivateUse:
+		return true
+	default:
+		return false
+	}
+}
+
+// Entry represents a parsed block. Highest cardinalities on 30/09/2022 are:
+//
+//	map[string]int{
+//		"Added":1,
+//		"Comments":1,
+//		"Deprecated":1,
+//		"Description":7,
+//		"File-Date":1,
+//		"Macrolanguage":1,
+//		"Preferred-Value":1,
+//		"Prefix":11,
+//		"Scope":1,
+//		"Subtag":1,
+//		"Suppress-Script":1,
+//		"Tag":1,
+//		"Type":1
+//		}
+type Entry struct {
+	Added          Date     `json:"added" yaml:"added"`                                 // date only
+	Comments       string   `json:"comments,omitempty" yaml:"comments,omitempty"`       // multiline
+	Deprecated     Date     `json:"deprecated,omitzero" yaml:"deprecated,omitempty"`    // date only
+	Description    []string `json:"description,omitempty" yaml:"description,omitempty"` // multiline
+	MacroLanguage  string   `json:"macro-language,omitempty" yaml:"macro-language,omitempty"`
+	PreferredValue string   `json:"preferred-value,omitempty" yaml:"preferred-value,omitempty"`
+	Prefix         []string `json:"prefix,omitempty" yaml:"prefix,omitempty"`                  // max: 11
+	Scope          Scope    `json:"scope,omitempty" yaml:"scope,omitempty"`                    // collection:116, macrolanguage:62, private-use:1, special:4
+	Subtag         string   `json:"subtag,omitempty" yaml:"subtag,omitempty"`                  // max length:10 "Qaaa..Qabx"
+	SuppressScript Script   `json:"suppress-script,omitzero" yaml:"suppress-script,omitempty"` // length: 4
+	Tag            string   `json:"tag,omitempty" yaml:"tag,omitempty"`                        // always contains a dash
+	Type           Type     `json:"type,omitempty" yaml:"type,omitempty"`                      // extlang:252,grandfathered:26, language:8240, redundant:67, region:304, script:212, variant:110
+}
+
+type Registry struct {
+	FileDate Date    `json:"file-date" yaml:"file-date"`
+	Entries  []Entry `json:"entries" yaml:"entries"`
+
+	// Source describes where the registry was read from, like its download URL,
+	// for the envelope of the json format. Parse leaves it empty.
+	Source string `json:"-" yaml:"-"`
+
+	index *indexCache // Shared by copies, built on first lookup.
+}
+
+// Parse reads a whole registry, the first block being the file-date block,
+// like ParseWith with the default Options.
+func Parse(r io.Reader) (*Registry, error) {
+	return ParseWith(r, Options{})
+}
+
+// ParseWith reads a whole registry like Parse. Unless opts.FailFast, it parses all the blocks
+// after a malformed one, returning all their ParseError values, joined.
+// With opts.Faithful, multi-line values keep their line breaks.
+func ParseWith(r io.Reader, opts Options) (*Registry, error) {
+	reg := &Registry{index: &indexCache{}}
+	err := streamEntries(r, opts, func(fd Date) error {
+		reg.FileDate = fd
+		return nil
+	}, func(e Entry) error {
+		reg.Entries = append(reg.Entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// parseFileDate parses the first block of a registry, holding its File-Date.
+func parseFileDate(bs []byte) (Date, error) {
+	dateBlock := lexBlock(string(bs))
+	fd, ok := dateBlock["file-date"]
+	if !ok {
+		return Date{}, fmt.Errorf("first block is not a file-date block: %q", dateBlock)
+	}
+	return parseDate("file-date", fd)
+}
+
+// lexBlock parses a block lexically, returning the lower-case keys and slices of values as strings.
+//
+// Values keep their line structure: the lines of values continued on the next rows are joined
+// by newlines, without their indentation. Use unfold to join them by spaces instead.
+//
+// Lines are trimmed of the padding at both ends, including the first line after the key,
+// so that folding only ever introduces a single separator, while the spacing within lines is kept.
+func lexBlock(bs string) map[string][]string {
+	m := make(map[string][]string, 20)
+	rows := strings.Split(bs, "\n")
+	var ck, cv string
+	for _, row := range rows {
+		if strings.TrimSpace(row) == "" {
+			continue
+		}
+		// New key: store the previous one
+		if key := PropRowRx.FindStringSubmatch(row); len(key) > 2 {
+			nk, nv := strings.ToLower(key[1]), strings.TrimSpace(key[2])
+
+			if ck != "" {
+				m[ck] = append(m[ck], cv)
+			}
+			ck, cv = nk, nv
+			continue
+		}
+		// Not a new key: append to the current value for the current key
+		if cv != "" {
+			cv += "\n"
+		}
+		cv += strings.TrimSpace(row)
+	}
+	if ck != "" {
+		m[ck] = append(m[ck], cv)
+	}
+	return m
+}
+
+// unfold joins the lines of the lexed values by spaces, as the registry folds long values
+// onto continuation lines which do not carry meaning.
+func unfold(lexed map[string][]string) {
+	for _, vs := range lexed {
+		for i, v := range vs {
+			vs[i] = strings.ReplaceAll(v, "\n", " ")
+		}
+	}
+}
+
+// CheckBlocks performs a sanity check on the start of a registry, to detect
+// truncated or garbled files: the first block must be a valid file-date block,
+// and it must be followed by at least one entry block.
+func CheckBlocks(r io.Reader) error {
+	bs := newBlockScanner(r)
+	if !bs.Scan() {
+		return errors.New("no blocks")
+	}
+	fd, ok := lexBlock(string(bs.Block()))["file-date"]
+	if !ok || len(fd) != 1 {
+		return errors.New("first block is not a file-date block")
+	}
+	if _, err := time.Parse("2006-01-02", fd[0]); err != nil {
+		return fmt.Errorf("invalid file-date: %w", err)
+	}
+	if !bs.Scan() {
+		return errors.New("no entry blocks")
+	}
+	return nil
+}
+
+// ReadFileDate reads the File-Date of a registry from its first block, without parsing the entries.
+func ReadFileDate(r io.Reader) (Date, error) {
+	bs := newBlockScanner(r)
+	if !bs.Scan() {
+		if err := bs.Err(); err != nil {
+			return Date{}, err
+		}
+		return Date{}, errors.New("empty registry")
+	}
+	return parseFileDate(bs.Block())
+}
+
+// Summary reads the File-Date of a registry and counts its entries, without parsing them:
+// the count is that of the entry blocks, which Parse might fail on.
+func Summary(r io.Reader) (fileDate Date, count int, err error) {
+	bs := newBlockScanner(r)
+	if !bs.Scan() {
+		if err = bs.Err(); err != nil {
+			return Date{}, 0, err
+		}
+		return Date{}, 0, errors.New("empty registry")
+	}
+	if fileDate, err = parseFileDate(bs.Block()); err != nil {
+		return Date{}, 0, err
+	}
+	for bs.Scan() {
+		count++
+	}
+	if err = bs.Err(); err != nil {
+		return Date{}, 0, err
+	}
+	return fileDate, count, nil
+}
+
+// blockScanner reads a registry one block at a time, the blocks being separated by "%%" lines.
+//
+// To support hand-edited files, separator lines may carry surrounding blanks,
+// and blank lines or repeated separators between blocks are ignored.
+type blockScanner struct {
+	sc    *bufio.Scanner
+	block []byte
+}
+
+func newBlockScanner(r io.Reader) *blockScanner {
+	return &blockScanner{sc: bufio.NewScanner(r)}
+}
+
+// Scan advances to the next block, returning false at the end of input or on a read error.
+func (bs *blockScanner) Scan() bool {
+	bs.block = nil
+	for bs.sc.Scan() {
+		line := bs.sc.Bytes()
+		if string(bytes.TrimSpace(line)) != "%%" {
+			bs.block = append(bs.block, line...)
+			bs.block = append(bs.block, '\n')
+			continue
+		}
+		if len(bytes.TrimSpace(bs.block)) != 0 {
+			return true
+		}
+		bs.block = nil
+	}
+	return len(bytes.TrimSpace(bs.block)) != 0
+}
+
+// Block returns the block read by the last call to Scan.
+func (bs *blockScanner) Block() []byte {
+	return bs.block
+}
+
+// Err returns the read error which ended scanning, if any.
+func (bs *blockScanner) Err() error {
+	return bs.sc.Err()
+}
+
+// StreamEntries parses a registry one block at a time, without accumulating the entries,
+// calling onFileDate with the File-Date of the registry, then onEntry with each entry
+// in registry order. It stops at the first parse error or error returned by a callback.
+func StreamEntries(r io.Reader, onFileDate func(Date) error, onEntry func(Entry) error) error {
+	return streamEntries(r, Options{FailFast: true}, onFileDate, onEntry)
+}
+
+// streamEntries implements StreamEntries. Unless opts.FailFast, it goes on after malformed entry blocks,
+// returning their errors once done, joined. Errors returned by callbacks, and exceeding
+// opts.MaxEntries, always stop it.
+func streamEntries(r io.Reader, opts Options, onFileDate func(Date) error, onEntry func(Entry) error) error {
+	bs := newBlockScanner(r)
+	if !bs.Scan() {
+		if err := bs.Err(); err != nil {
+			return err
+		}
+		return errors.New("empty registry")
+	}
+	fd, err := parseFileDate(bs.Block())
+	if err != nil {
+		return &ParseError{Block: 0, Key: "file-date", Err: err}
+	}
+	if err = onFileDate(fd); err != nil {
+		return err
+	}
+	var errs []error
+	for i := 1; bs.Scan(); i++ {
+		if opts.MaxEntries > 0 && i > opts.MaxEntries {
+			return fmt.Errorf("%w: more than %d", ErrTooManyEntries, opts.MaxEntries)
+		}
+		lexed := lexBlock(string(bs.Block()))
+		if !opts.Faithful {
+			unfold(lexed)
+		}
+		e, err := parseBlock(lexed)
+		if err != nil {
+			var pe *ParseError
+			if errors.As(err, &pe) {
+				pe.Block = i
+			}
+			if opts.FailFast {
+				return err
+			}
+			errs = append(errs, err)
+			continue
+		}
+		if err = onEntry(e); err != nil {
+			return err
+		}
+	}
+	return errors.Join(append(errs, bs.Err())...)
+}
+
+// ParseStream is like StreamEntries, for callers only needing the File-Date once done,
+// like database loaders: fn receives each entry in registry order, and the File-Date is returned.
+// The entry passed to fn is only valid during the call.
+func ParseStream(r io.Reader, fn func(*Entry) error) (fileDate Date, err error) {
+	err = StreamEntries(r, func(fd Date) error {
+		fileDate = fd
+		return nil
+	}, func(e Entry) error {
+		return fn(&e)
+	})
+	return fileDate, err
+}
+
+func parseBlock(lexed map[string][]string) (Entry, error) {
+	var (
+		e   Entry
+		err error
+	)
+	// Report the first malformed field in key order, not in the random order of the map.
+	for _, k := range slices.Sorted(maps.Keys(lexed)) {
+		vs := lexed[k]
+		// PropRowRx accepts an empty value, to be continued on the next lines, but not a field left empty.
+		if slices.Contains(vs, "") {
+			return Entry{}, &ParseError{Key: k, Value: strings.Join(vs, "\n"), Err: fmt.Errorf("key %s has an empty value", k)}
+		}
+		switch k {
+		case "added":
+			e.Added, err = parseDate(k, vs)
+		case "comments":
+			e.Comments, err = parseString(k, vs)
+		case "deprecated":
+			e.Deprecated, err = parseDate(k, vs)
+		case "description":
+			e.Description = vs
+		case "macrolanguage":
+			e.MacroLanguage, err = parseString(k, vs)
+		case "preferred-value":
+			e.PreferredValue, err = parseString(k, vs)
+		case "prefix":
+			e.Prefix = vs
+		case "scope":
+			e.Scope, err = parseScope(k, vs)
+		case "subtag":
+			e.Subtag, err = parseString(k, vs)
+		case "suppress-script":
+			e.SuppressScript, err = parseScript(k, vs)
+		case "tag":
+			e.Tag, err = parseString(k, vs)
+		case "type":
+			e.Type, err = parseType(k, vs)
+		default:
+			err = fmt.Errorf("unexpected key: %q", k)
+		}
+		if err != nil {
+			return Entry{}, &ParseError{Key: k, Value: strings.Join(vs, "\n"), Err: err}
+		}
+	}
+	return e, nil
+}
+
+func parseDate(k string, vs []string) (Date, error) {
+	v, err := parseString(k, vs)
+	if err != nil {
+		return Date{}, err
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return Date{}, fmt.Errorf("key %s failed parsing value %q: %w", k, v, err)
+	}
+	return Date(t), nil
+}
+
+func parseScript(k string, vs []string) (Script, error) {
+	v, err := parseString(k, vs)
+	if err != nil {
+		return Script{}, err
+	}
+	if len(v) != 4 {
+		return Script{}, fmt.Errorf("key %s has language with len != 4: %q", k, v)
+	}
+	// Script codes are in ASCII.
+	fixed := [4]rune{}
+	for i := 0; i < len(v); i++ {
+		fixed[i] = rune(v[i])
+	}
+	return fixed, nil
+}
+
+func parseType(k string, vs []string) (Type, error) {
+	v, err := parseString(k, vs)
+	if err != nil {
+		return "", err
+	}
+	if t := Type(v); t.IsValid() {
+		return t, nil
+	}
+	return "", fmt.Errorf("key %s has unknown type %q", k, v)
+}
+
+func parseScope(k string, vs []string) (Scope, error) {
+	v, err := parseString(k, vs)
+	if err != nil {
+		return "", err
+	}
+	if s := Scope(v); s.IsValid() {
+		return s, nil
+	}
+	return "", fmt.Errorf("key %s has unknown scope %q", k, v)
+}
+
+func parseString(k string, vs []string) (string, error) {
+	if len(vs) != 1 {
+		return "", fmt.Errorf("key %s has value with length %d != 1", k, len(vs))
+	}
+	return vs[0], nil
+}
@@ -0,0 +1,465 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestCheckBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{"valid", "File-Date: 2023-08-02\n%%\nType: language\nSubtag: de\n", ""},
+		{"empty", "", "no blocks"},
+		{"no entry blocks", "File-Date: 2023-08-02\n%%\n", "no entry blocks"},
+		{"no file-date", "Type: language\nSubtag: de\n%%\nType: language\n", "first block is not a file-date block"},
+		{"truncated file-date", "File-Date: 2023-0\n%%\nType: language\n", "invalid file-date"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckBlocks(strings.NewReader(test.text))
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("CheckBlocks() = %v, want nil", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("CheckBlocks() = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse_separators(t *testing.T) {
+	const entries = "Type: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n" +
+		"%%\nType: region\nSubtag: DE\nDescription: Germany\nAdded: 2005-10-16\n"
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"standard", "File-Date: 2023-08-02\n%%\n" + entries},
+		{"bare separators", "File-Date: 2023-08-02\n%%\nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n" +
+			"%%\nType: region\nSubtag: DE\nDescription: Germany\nAdded: 2005-10-16"},
+		{"blank lines", "File-Date: 2023-08-02\n\n%%\n\nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n\n\n" +
+			"%%\n\nType: region\nSubtag: DE\nDescription: Germany\nAdded: 2005-10-16\n\n"},
+		{"padded separators", "File-Date: 2023-08-02\n  %% \nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n" +
+			"\t%%\nType: region\nSubtag: DE\nDescription: Germany\nAdded: 2005-10-16\n"},
+		{"repeated separators", "File-Date: 2023-08-02\n%%\n%%\n" + strings.Replace(entries, "%%\n", "%%\n\n%%\n", 1) + "%%\n"},
+		{"CRLF", strings.ReplaceAll("File-Date: 2023-08-02\n%%\n"+entries, "\n", "\r\n")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := mustParse(t, test.text)
+			if got, want := keys(r.Entries), []string{"de", "DE"}; r.FileDate.String() != "2023-08-02" || !slices.Equal(got, want) {
+				t.Errorf("Parse() = %s %q, want 2023-08-02 %q", r.FileDate, got, want)
+			}
+			if d := r.Entries[1].Description; !slices.Equal(d, []string{"Germany"}) {
+				t.Errorf("Parse() has description %q, want %q", d, "Germany")
+			}
+		})
+	}
+}
+
+func TestLexBlock_colonSpacing(t *testing.T) {
+	tests := []struct {
+		name string
+		row  string
+	}{
+		{"standard", "Type: language"},
+		{"no space", "Type:language"},
+		{"spaces", "Type:   language"},
+		{"trailing spaces", "Type: language  "},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lexed := lexBlock(test.row + "\nSubtag:de\nDescription:German\n  Deutsch\n")
+			want := map[string][]string{"type": {"language"}, "subtag": {"de"}, "description": {"German\nDeutsch"}}
+			if len(lexed) != len(want) {
+				t.Fatalf("lexBlock() = %q, want %q", lexed, want)
+			}
+			for k, v := range want {
+				if !slices.Equal(lexed[k], v) {
+					t.Errorf("lexBlock()[%q] = %q, want %q", k, lexed[k], v)
+				}
+			}
+		})
+	}
+	// Parsing yields a typed entry, instead of a continuation of the previous field.
+	r := mustParse(t, "File-Date: 2023-08-02\n%%\nType:language\nSubtag:de\nDescription:German\nAdded:2005-10-16\n")
+	if e := r.Entries[0]; e.Type != TypeLanguage || e.Subtag != "de" || e.Added.String() != "2005-10-16" {
+		t.Errorf("Parse() = %+v, want language de added on 2005-10-16", e)
+	}
+}
+
+func TestLexBlock_folding(t *testing.T) {
+	// The 1694acad block of the registry, with its multi-line Comments.
+	const block = "Type: variant\nSubtag: 1694acad\nDescription: Early Modern French\nAdded: 2007-03-20\nPrefix: fr\n" +
+		"Comments: 17th century French, as catalogued in the \"Dictionnaire de\n" +
+		"  l'académie françoise\", 4eme ed. 1694; frequently includes elements of\n" +
+		"  Middle French, as this is a transitional period\n"
+	const want = `17th century French, as catalogued in the "Dictionnaire de l'académie françoise", ` +
+		"4eme ed. 1694; frequently includes elements of Middle French, as this is a transitional period"
+	tests := []struct {
+		name  string
+		block string
+		want  string
+	}{
+		{"registry", block, want},
+		{"deeper indentation", strings.ReplaceAll(block, "\n  ", "\n\t    "), want},
+		{"trailing padding", strings.ReplaceAll(block, "\n", "  \n"), want},
+		{"padded first line", strings.Replace(block, "Comments: ", "Comments:   ", 1), want},
+		{"value on the next line", strings.Replace(block, "Comments: ", "Comments:\n  ", 1), want},
+		{"spacing within a line", strings.Replace(block, "4eme ed. 1694", "4eme ed.  1694", 1),
+			strings.Replace(want, "4eme ed. 1694", "4eme ed.  1694", 1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lexed := lexBlock(test.block)
+			unfold(lexed)
+			if got := lexed["comments"]; len(got) != 1 || got[0] != test.want {
+				t.Errorf("unfolded comments = %q, want %q", got, test.want)
+			}
+			if got := lexed["description"]; !slices.Equal(got, []string{"Early Modern French"}) {
+				t.Errorf("unfolded description = %q, want %q", got, "Early Modern French")
+			}
+			// Only fields left empty are malformed.
+			if _, err := parseBlock(lexed); err != nil {
+				t.Errorf("parseBlock() = %v", err)
+			}
+		})
+	}
+	// The entry of the registry file, parsed as a whole, has the same Comments.
+	if e, ok := parseTestdata(t, Options{}).BySubtag("1694acad"); !ok || e.Comments != want {
+		t.Errorf("parsed Comments = %q, want %q", e.Comments, want)
+	}
+}
+
+// parseErrorOf parses a registry expecting a single ParseError, failing the test otherwise.
+func parseErrorOf(t *testing.T, text string) *ParseError {
+	t.Helper()
+	r, err := Parse(strings.NewReader(text))
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Parse() = %v, %v, want a *ParseError", r, err)
+	}
+	if !errors.Is(err, ErrMalformedBlock) {
+		t.Errorf("Parse() = %v, want it to match ErrMalformedBlock", err)
+	}
+	return pe
+}
+
+func TestParse_errors(t *testing.T) {
+	const head = "File-Date: 2023-08-02\n%%\nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n%%\n"
+	tests := []struct {
+		name      string
+		text      string
+		wantBlock int
+		wantKey   string
+		wantValue string
+		wantErr   string
+	}{
+		{"invalid file-date", "File-Date: 2023-13-01\n%%\nType: language\n", 0, "file-date", "", `failed parsing value "2023-13-01"`},
+		{"invalid date", head + "Type: language\nSubtag: fr\nAdded: 16/10/2005\n", 2, "added", "16/10/2005", `key added failed parsing value "16/10/2005"`},
+		{"invalid script", head + "Type: language\nSubtag: fr\nSuppress-Script: Latin\n", 2, "suppress-script", "Latin", "len != 4"},
+		{"repeated single-valued key", head + "Type: language\nSubtag: fr\nSubtag: fra\n", 2, "subtag", "fr\nfra", "length 2 != 1"},
+		{"unexpected key", head + "Type: language\nSubtag: fr\nColour: blue\n", 2, "colour", "blue", `unexpected key: "colour"`},
+		{"unknown type", head + "Type: dialect\nSubtag: fr\n", 2, "type", "dialect", `key type has unknown type "dialect"`},
+		{"type case", head + "Type: Language\nSubtag: fr\n", 2, "type", "Language", `unknown type "Language"`},
+		{"unknown scope", head + "Type: language\nSubtag: fr\nScope: dialect\n", 2, "scope", "dialect", `key scope has unknown scope "dialect"`},
+		{"empty subtag", head + "Type: language\nSubtag:\nDescription: French\n", 2, "subtag", "", "key subtag has an empty value"},
+		{"empty subtag with a space", head + "Type: language\nSubtag: \nDescription: French\n", 2, "subtag", "", "key subtag has an empty value"},
+		{"empty description", head + "Type: language\nSubtag: fr\nDescription: French\nDescription:\n", 2, "description", "French\n", "key description has an empty value"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pe := parseErrorOf(t, test.text)
+			if pe.Block != test.wantBlock || pe.Key != test.wantKey || (test.wantValue != "" && pe.Value != test.wantValue) {
+				t.Errorf("ParseError is %+v, want block %d, key %q, value %q", pe, test.wantBlock, test.wantKey, test.wantValue)
+			}
+			if !strings.Contains(pe.Error(), test.wantErr) {
+				t.Errorf("ParseError is %q, want it to contain %q", pe, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestType_IsValid(t *testing.T) {
+	tests := []struct {
+		typ  Type
+		want bool
+	}{
+		{TypeLanguage, true},
+		{TypeExtlang, true},
+		{TypeScript, true},
+		{TypeRegion, true},
+		{TypeVariant, true},
+		{TypeGrandfathered, true},
+		{TypeRedundant, true},
+		{"dialect", false},
+		{"Language", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		t.Run(test.typ.String(), func(t *testing.T) {
+			if got := test.typ.IsValid(); got != test.want {
+				t.Errorf("Type(%q).IsValid() = %t, want %t", test.typ, got, test.want)
+			}
+		})
+	}
+}
+
+func TestScope_IsValid(t *testing.T) {
+	tests := []struct {
+		scope Scope
+		want  bool
+	}{
+		{ScopeMacrolanguage, true},
+		{ScopeCollection, true},
+		{ScopeSpecial, true},
+		{ScopePrivateUse, true},
+		{"private", false},
+		{"", false}, // No Scope, which is not written.
+	}
+	for _, test := range tests {
+		t.Run(test.scope.String(), func(t *testing.T) {
+			if got := test.scope.IsValid(); got != test.want {
+				t.Errorf("Scope(%q).IsValid() = %t, want %t", test.scope, got, test.want)
+			}
+			e := Entry{Type: TypeLanguage, Subtag: "xx", Scope: test.scope}
+			for name, marshal := range map[string]func(any) ([]byte, error){"json": json.Marshal, "yaml": yaml.Marshal} {
+				bs, err := marshal(e)
+				if err != nil {
+					t.Fatalf("%s marshalling failed: %v", name, err)
+				}
+				if got := strings.Contains(string(bs), "scope"); got != (test.scope != "") {
+					t.Errorf("%s output %s has a scope: %t, want %t", name, bs, got, test.scope != "")
+				}
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	testdata, err := os.ReadFile(testdataRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const entry = "Type: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n"
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{"testdata", string(testdata), ""},
+		{"no entries", "File-Date: 2023-08-02\n", ""},
+		{"blank lines and repeated separators", "File-Date: 2023-08-02\n%%\n\n%%\n" + entry + "%%\n\n" + entry, ""},
+		{"empty", "", "empty registry"},
+		{"no file-date", entry, "first block is not a file-date block"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileDate, count, err := Summary(strings.NewReader(test.text))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("Summary() = %s, %d, %v, want error %q", fileDate, count, err, test.wantErr)
+				}
+				return
+			}
+			r := mustParse(t, test.text)
+			if err != nil || !fileDate.Equal(r.FileDate) || count != len(r.Entries) {
+				t.Errorf("Summary() = %s, %d, %v, want %s, %d like Parse", fileDate, count, err, r.FileDate, len(r.Entries))
+			}
+		})
+	}
+}
+
+func TestParseStream(t *testing.T) {
+	testdata, err := os.ReadFile(testdataRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const head = "File-Date: 2023-08-02\n%%\nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n%%\n"
+	errStop := errors.New("stop")
+	tests := []struct {
+		name      string
+		text      string
+		stopAfter int // Entries after which fn fails, or 0 for none.
+		want      int // Entries received.
+		wantErr   string
+	}{
+		{"testdata", string(testdata), 0, 49, ""},
+		{"callback failure", string(testdata), 3, 3, "stop"},
+		{"malformed block", head + "Type: dialect\n%%\nType: language\nSubtag: fr\n", 0, 1, `unknown type "dialect"`},
+		{"no file-date", "Type: language\nSubtag: de\n", 0, 0, "first block is not a file-date block"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			fileDate, err := ParseStream(strings.NewReader(test.text), func(e *Entry) error {
+				got = append(got, e.Key())
+				if len(got) == test.stopAfter {
+					return errStop
+				}
+				return nil
+			})
+			if test.wantErr == "" && err != nil || test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Fatalf("ParseStream() = %v, want error %q", err, test.wantErr)
+			}
+			if len(got) != test.want {
+				t.Errorf("ParseStream() passed %d entries, want %d", len(got), test.want)
+			}
+			if test.want > 0 && fileDate.String() != "2023-08-02" {
+				t.Errorf("ParseStream() = %s, want File-Date 2023-08-02", fileDate)
+			}
+			if test.name == "testdata" {
+				if want := keys(mustParse(t, test.text).Entries); !slices.Equal(got, want) {
+					t.Errorf("ParseStream() passed %q, want the entries of Parse %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseWith_failFast(t *testing.T) {
+	const text = "File-Date: 2023-08-02\n" +
+		"%%\nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n" +
+		"%%\nType: language\nSubtag: fr\nAdded: 16/10/2005\n" +
+		"%%\nType: region\nSubtag: DE\nDescription: Germany\nAdded: 2005-10-16\n" +
+		"%%\nType: dialect\nSubtag: xx\n"
+	tests := []struct {
+		name       string
+		opts       Options
+		wantBlocks []int
+	}{
+		{"collect all", Options{}, []int{2, 4}},
+		{"fail fast", Options{FailFast: true}, []int{2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := ParseWith(strings.NewReader(text), test.opts)
+			if r != nil || err == nil {
+				t.Fatalf("ParseWith() = %+v, %v, want an error", r, err)
+			}
+			var errs []error
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				errs = joined.Unwrap()
+			} else {
+				errs = []error{err}
+			}
+			var blocks []int
+			for _, err := range errs {
+				var pe *ParseError
+				if !errors.As(err, &pe) {
+					t.Fatalf("ParseWith() error %v is not a ParseError", err)
+				}
+				blocks = append(blocks, pe.Block)
+			}
+			if !slices.Equal(blocks, test.wantBlocks) {
+				t.Errorf("ParseWith() = %v, for blocks %v, want blocks %v", err, blocks, test.wantBlocks)
+			}
+		})
+	}
+}
+
+func TestParseWith_faithful(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	faithful := parseTestdata(t, Options{Faithful: true})
+	tests := []struct {
+		subtag string
+		want   []string // The lines of the Comments in the registry.
+	}{
+		{"1694acad", []string{
+			`17th century French, as catalogued in the "Dictionnaire de`,
+			`l'académie françoise", 4eme ed. 1694; frequently includes elements of`,
+			"Middle French, as this is a transitional period",
+		}},
+		{"alalc97", []string{
+			"Romanizations recommended by the American Library Association",
+			`and the Library of Congress, in "ALA-LC Romanization Tables:`,
+			`Transliteration Schemes for Non-Roman Scripts" (1997), ISBN`,
+			"978-0-8444-0940-5.",
+		}},
+		{"sh", []string{"sr, hr, bs are preferred for most modern uses"}},
+	}
+	for _, test := range tests {
+		t.Run(test.subtag, func(t *testing.T) {
+			e, ok := faithful.BySubtag(test.subtag)
+			if !ok || e.Comments != strings.Join(test.want, "\n") {
+				t.Errorf("faithful Comments = %q, want %q", e.Comments, strings.Join(test.want, "\n"))
+			}
+			e, ok = r.BySubtag(test.subtag)
+			if !ok || e.Comments != strings.Join(test.want, " ") {
+				t.Errorf("Comments = %q, want %q", e.Comments, strings.Join(test.want, " "))
+			}
+		})
+	}
+}
+
+func TestOptions_MaxEntries(t *testing.T) {
+	const text = testRegistryHead +
+		"%%\nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n" +
+		"%%\nType: language\nSubtag: fr\nDescription: French\nAdded: 2005-10-16\n" +
+		"%%\nType: region\nSubtag: DE\nDescription: Germany\nAdded: 2005-10-16\n"
+	tests := []struct {
+		name       string
+		maxEntries int
+		wantErr    string
+	}{
+		{"exceeded", 2, "registry has too many entries: more than 2"},
+		{"reached", 3, ""},
+		{"unlimited", 0, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := Options{MaxEntries: test.maxEntries}
+			r, err := ParseWith(strings.NewReader(text), opts)
+			var buf bytes.Buffer
+			serr := StreamYAML(&buf, strings.NewReader(text), opts)
+			if test.wantErr != "" {
+				if !errors.Is(err, ErrTooManyEntries) || err.Error() != test.wantErr {
+					t.Errorf("ParseWith() = %v, want error %q matching ErrTooManyEntries", err, test.wantErr)
+				}
+				if !errors.Is(serr, ErrTooManyEntries) || serr.Error() != test.wantErr {
+					t.Errorf("StreamYAML() = %v, want error %q matching ErrTooManyEntries", serr, test.wantErr)
+				}
+				return
+			}
+			if err != nil || len(r.Entries) != 3 {
+				t.Fatalf("ParseWith() = %v, want 3 entries", err)
+			}
+			if serr != nil || buf.String() != encode(t, *r, FormatYAML, opts) {
+				t.Errorf("StreamYAML() = %v, writing:\n%s", serr, buf.String())
+			}
+		})
+	}
+}
+
+func TestParse_errorsSeveralFields(t *testing.T) {
+	const head = "File-Date: 2023-08-02\n%%\n"
+	tests := []struct {
+		name    string
+		text    string
+		wantKey string
+	}{
+		{"type and date", head + "Type: dialect\nSubtag: fr\nAdded: 16/10/2005\n", "added"},
+		{"scope and script", head + "Type: language\nSubtag: fr\nSuppress-Script: Latin\nScope: dialect\n", "scope"},
+		{"three fields", head + "Type: dialect\nSubtag: fr\nSubtag: fra\nScope: dialect\n", "scope"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Map iteration order varies between runs, so parse repeatedly.
+			for range 20 {
+				if pe := parseErrorOf(t, test.text); pe.Key != test.wantKey {
+					t.Fatalf("ParseError is %+v, want key %q", pe, test.wantKey)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ExpandRanges replaces each entry for a range of subtags, like "qaa..qtz" or "Qaaa..Qabx",
+// by one entry per subtag in the range, in order, sharing the other fields of the range entry.
+// The subtags keep the letter case of the start of the range, like "Qaab".
+//
+// It fails if the endpoints of a range differ in length, or in the kind of character,
+// letter or digit, at some position, or if the range is reversed.
+func (r *Registry) ExpandRanges() error {
+	entries := make([]Entry, 0, len(r.Entries))
+	for i, e := range r.Entries {
+		lo, hi, ok := strings.Cut(e.Subtag, "..")
+		if !ok {
+			entries = append(entries, e)
+			continue
+		}
+		subtags, err := expandRange(lo, hi)
+		if err != nil {
+			return entryError(i, e, "%v", err)
+		}
+		for _, s := range subtags {
+			x := e
+			x.Subtag = s
+			x.Description, x.Prefix = slices.Clone(e.Description), slices.Clone(e.Prefix)
+			entries = append(entries, x)
+		}
+	}
+	r.Entries = entries
+	return nil
+}
+
+// expandRange returns the subtags from lo to hi, both included, in order.
+func expandRange(lo, hi string) ([]string, error) {
+	if len(lo) != len(hi) {
+		return nil, fmt.Errorf("range %s..%s has endpoints of different lengths", lo, hi)
+	}
+	for i := 0; i < len(lo); i++ {
+		l, h := lo[i:i+1], hi[i:i+1]
+		if !(isAlpha(l) && isAlpha(h) || isDigits(l) && isDigits(h)) {
+			return nil, fmt.Errorf("range %s..%s mixes letters and digits at position %d", lo, hi, i+1)
+		}
+	}
+	if strings.ToLower(lo) > strings.ToLower(hi) {
+		return nil, fmt.Errorf("range %s..%s is reversed", lo, hi)
+	}
+	var res []string
+	cur := []byte(lo)
+	for {
+		res = append(res, string(cur))
+		if strings.EqualFold(string(cur), hi) {
+			return res, nil
+		}
+		increment(cur)
+	}
+}
+
+// increment advances the subtag s to the next one, carrying from position to position.
+func increment(s []byte) {
+	for i := len(s) - 1; i >= 0; i-- {
+		switch s[i] {
+		case 'z':
+			s[i] = 'a'
+		case 'Z':
+			s[i] = 'A'
+		case '9':
+			s[i] = '0'
+		default:
+			s[i]++
+			return
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExpandRange(t *testing.T) {
+	tests := []struct {
+		lo, hi  string
+		want    []string // First and last subtags.
+		count   int
+		wantErr string
+	}{
+		{"qaa", "qtz", []string{"qaa", "qtz"}, 20 * 26, ""},
+		{"Qaaa", "Qabx", []string{"Qaaa", "Qabx"}, 26 + 24, ""},
+		{"QM", "QZ", []string{"QM", "QZ"}, 14, ""},
+		{"XA", "XZ", []string{"XA", "XZ"}, 26, ""},
+		{"a8", "b1", []string{"a8", "b1"}, 4, ""}, // Carries over mixed positions.
+		{"qaa", "qaa", []string{"qaa", "qaa"}, 1, ""},
+		{"qaa", "qtzz", nil, 0, "range qaa..qtzz has endpoints of different lengths"},
+		{"qa1", "qtz", nil, 0, "range qa1..qtz mixes letters and digits at position 3"},
+		{"qtz", "qaa", nil, 0, "range qtz..qaa is reversed"},
+	}
+	for _, test := range tests {
+		t.Run(test.lo+".."+test.hi, func(t *testing.T) {
+			got, err := expandRange(test.lo, test.hi)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("expandRange() = %q, %v, want error %q", got, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || len(got) != test.count || got[0] != test.want[0] || got[len(got)-1] != test.want[1] {
+				t.Fatalf("expandRange() = %d subtags, %v, want %d from %s to %s", len(got), err, test.count, test.want[0], test.want[1])
+			}
+			if !slices.IsSortedFunc(got, func(a, b string) int { return strings.Compare(strings.ToLower(a), strings.ToLower(b)) }) || len(slices.Compact(slices.Clone(got))) != len(got) {
+				t.Errorf("expandRange() = %q, want distinct subtags in order", got)
+			}
+		})
+	}
+}
+
+func TestRegistry_ExpandRanges(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	n := len(r.Entries)
+	if err := r.ExpandRanges(); err != nil {
+		t.Fatalf("ExpandRanges() = %v", err)
+	}
+	// Each range entry is replaced by its subtags.
+	if want := n - 2 + 20*26 + 26 + 24; len(r.Entries) != want {
+		t.Errorf("ExpandRanges() made %d entries, want %d", len(r.Entries), want)
+	}
+	tests := []struct {
+		subtag string
+		typ    Type
+		want   bool
+	}{
+		{"qab", TypeLanguage, true},
+		{"qtz", TypeLanguage, true},
+		{"qua", TypeLanguage, false},
+		{"Qaax", TypeScript, true},
+		{"Qaby", TypeScript, false},
+		{"qaa..qtz", TypeLanguage, false},
+	}
+	for _, test := range tests {
+		t.Run(test.subtag, func(t *testing.T) {
+			e, ok := r.BySubtagAndType(test.subtag, test.typ)
+			if ok != test.want {
+				t.Fatalf("BySubtagAndType(%q) = %t, want %t", test.subtag, ok, test.want)
+			}
+			if ok && (e.Subtag != test.subtag || e.Added.IsZero() || len(e.Description) == 0 || !strings.HasPrefix(e.Description[0], "Private use")) {
+				t.Errorf("expanded entry %+v does not share the range fields", e)
+			}
+		})
+	}
+}
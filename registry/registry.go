@@ -0,0 +1,426 @@
+package registry
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Key returns the Subtag of an entry, or its Tag for grandfathered and redundant entries.
+func (e Entry) Key() string {
+	if e.Subtag != "" {
+		return e.Subtag
+	}
+	return e.Tag
+}
+
+// ReplacementGroups maps each Preferred-Value to the deprecated entries replaced by it,
+// in registry order. Non-deprecated entries carrying a Preferred-Value, like extlangs, are ignored.
+func (r Registry) ReplacementGroups() map[string][]Entry {
+	groups := make(map[string][]Entry)
+	for _, e := range r.Entries {
+		if e.Deprecated.IsZero() || e.PreferredValue == "" {
+			continue
+		}
+		groups[e.PreferredValue] = append(groups[e.PreferredValue], e)
+	}
+	return groups
+}
+
+// NonASCIIDescriptions returns the entries having a non-ASCII rune in any of their descriptions.
+func (r Registry) NonASCIIDescriptions() []Entry {
+	var res []Entry
+	for _, e := range r.Entries {
+		for _, d := range e.Description {
+			if strings.IndexFunc(d, func(c rune) bool { return c > unicode.MaxASCII }) != -1 {
+				res = append(res, e)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// CommentsMentioning returns the entries whose Comments contain substr, compared
+// case-insensitively, like "rfc 5646", in registry order.
+func (r Registry) CommentsMentioning(substr string) []Entry {
+	substr = strings.ToLower(substr)
+	var res []Entry
+	for _, e := range r.Entries {
+		if e.Comments != "" && strings.Contains(strings.ToLower(e.Comments), substr) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// SearchDescription returns the entries having q in any of their descriptions, compared
+// case-insensitively, like "swiss german" for "gsw", in registry order.
+func (r Registry) SearchDescription(q string) []Entry {
+	q = strings.ToLower(q)
+	return r.Filter(func(e *Entry) bool {
+		return slices.ContainsFunc(e.Description, func(d string) bool {
+			return strings.Contains(strings.ToLower(d), q)
+		})
+	})
+}
+
+// ByInitial groups language entries by the lower-cased first rune of their Subtag, in registry order.
+func (r Registry) ByInitial() map[rune][]Entry {
+	groups := make(map[rune][]Entry)
+	for _, e := range r.Entries {
+		if e.Type != TypeLanguage || e.Subtag == "" {
+			continue
+		}
+		initial, _ := utf8.DecodeRuneInString(e.Subtag)
+		initial = unicode.ToLower(initial)
+		groups[initial] = append(groups[initial], e)
+	}
+	return groups
+}
+
+// AddedInRelease returns the entries Added on the registry File-Date, i.e. the latest batch of additions.
+func (r Registry) AddedInRelease() []Entry {
+	var res []Entry
+	for _, e := range r.Entries {
+		if e.Added.Equal(r.FileDate) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// ChangedOnFileDate returns the entries Added or Deprecated on the registry File-Date,
+// i.e. the changes of the latest release, in registry order. Without a File-Date, there are none.
+func (r Registry) ChangedOnFileDate() []Entry {
+	if r.FileDate.IsZero() {
+		return nil
+	}
+	var res []Entry
+	for _, e := range r.Entries {
+		if e.Added.Equal(r.FileDate) || e.Deprecated.Equal(r.FileDate) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// IsSpecial reports whether the entry has the special scope, like "und" or "zxx".
+// Such subtags are valid, but do not designate a specific language.
+func (e Entry) IsSpecial() bool {
+	return e.Scope == ScopeSpecial
+}
+
+// Specials returns the entries with the special scope, in registry order.
+func (r Registry) Specials() []Entry {
+	var res []Entry
+	for _, e := range r.Entries {
+		if e.IsSpecial() {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// Reordered returns the registry entries for the given subtags or tags first, in that order,
+// followed by the other entries in registry order. Keys are case-insensitive, and
+// entries sharing a key, like the "cmn" language and extlang, appear in registry order.
+//
+// Keys not found in the registry are returned as unknown.
+func (r Registry) Reordered(keys []string) (entries []Entry, unknown []string) {
+	idx := make(map[string][]int, len(r.Entries))
+	for i, e := range r.Entries {
+		k := indexKey(e)
+		idx[k] = append(idx[k], i)
+	}
+	used := make([]bool, len(r.Entries))
+	entries = make([]Entry, 0, len(r.Entries))
+	for _, k := range keys {
+		is, ok := idx[strings.ToLower(k)]
+		if !ok {
+			unknown = append(unknown, k)
+			continue
+		}
+		for _, i := range is {
+			if !used[i] {
+				used[i] = true
+				entries = append(entries, r.Entries[i])
+			}
+		}
+	}
+	for i, e := range r.Entries {
+		if !used[i] {
+			entries = append(entries, e)
+		}
+	}
+	return entries, unknown
+}
+
+// LanguagesSuppressing returns the language entries whose Suppress-Script is the given script code,
+// compared case-insensitively.
+func (r Registry) LanguagesSuppressing(script string) []Entry {
+	var res []Entry
+	for _, e := range r.Entries {
+		if e.Type == TypeLanguage && !e.SuppressScript.IsZero() && strings.EqualFold(e.SuppressScript.String(), script) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// MissingDescription returns the subtag entries without a Description, in registry order.
+//
+// Tag entries, i.e. grandfathered and redundant ones, are not returned: a Description
+// adds little to a full tag, so edited registries may legitimately omit it for them.
+func (r Registry) MissingDescription() []Entry {
+	var res []Entry
+	for _, e := range r.Entries {
+		if len(e.Description) == 0 && e.Tag == "" {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// All returns an iterator over the registry entries, in registry order.
+func (r Registry) All() iter.Seq[Entry] {
+	return func(yield func(Entry) bool) {
+		for _, e := range r.Entries {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// Filtered returns an iterator over the registry entries matching pred, in registry order.
+func (r Registry) Filtered(pred func(Entry) bool) iter.Seq[Entry] {
+	return func(yield func(Entry) bool) {
+		for e := range r.All() {
+			if pred(e) && !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns the registry entries matching pred, in registry order.
+// Unlike Filtered, pred receives pointers to the entries in Entries, avoiding their copy:
+// it must not modify them.
+func (r *Registry) Filter(pred func(*Entry) bool) []Entry {
+	var res []Entry
+	for i := range r.Entries {
+		if pred(&r.Entries[i]) {
+			res = append(res, r.Entries[i])
+		}
+	}
+	return res
+}
+
+// ByType returns the entries of the given type, in registry order.
+func (r Registry) ByType(typ Type) []Entry {
+	return r.Filter(func(e *Entry) bool { return e.Type == typ })
+}
+
+// Deprecated returns the deprecated entries, in registry order.
+func (r Registry) Deprecated() []Entry {
+	return r.Filter(func(e *Entry) bool { return !e.Deprecated.IsZero() })
+}
+
+// Extlangs maps each extlang subtag to its Preferred-Value, the language subtag to use instead,
+// like "yue" for the extlang of "zh-yue". Extlangs without a Preferred-Value map to "".
+func (r Registry) Extlangs() map[string]string {
+	extlangs := make(map[string]string)
+	for _, e := range r.Entries {
+		if e.Type == TypeExtlang {
+			extlangs[e.Subtag] = e.PreferredValue
+		}
+	}
+	return extlangs
+}
+
+// MacroTree maps each macrolanguage subtag to its member language entries, in registry order.
+func (r Registry) MacroTree() map[string][]Entry {
+	tree := make(map[string][]Entry)
+	for _, e := range r.Entries {
+		if e.Type == TypeLanguage && e.MacroLanguage != "" {
+			tree[e.MacroLanguage] = append(tree[e.MacroLanguage], e)
+		}
+	}
+	return tree
+}
+
+// WriteMacroTree renders the MacroTree as text: one line per macrolanguage, in subtag order,
+// followed by one indented line per member language.
+func (r Registry) WriteMacroTree(w io.Writer) error {
+	tree := r.MacroTree()
+	macros := make([]string, 0, len(tree))
+	for m := range tree {
+		macros = append(macros, m)
+	}
+	sort.Strings(macros)
+	for _, m := range macros {
+		name := m
+		if e, ok := r.Lookup(m, TypeLanguage); ok {
+			name = firstDescription(e)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", m, name); err != nil {
+			return err
+		}
+		for _, e := range tree[m] {
+			if _, err := fmt.Fprintf(w, "  %s: %s\n", e.Subtag, firstDescription(e)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyOverlay replaces the Description of the entries whose subtag or tag, compared
+// case-insensitively, is a key in overlay, by the overlay value. This is mostly useful to
+// name private-use subtags. Other fields are left untouched.
+//
+// Overlay keys matching no entry are returned as unknown, in key order.
+func (r *Registry) ApplyOverlay(overlay map[string]string) (unknown []string) {
+	used := make(map[string]bool, len(overlay))
+	lower := make(map[string]string, len(overlay))
+	for k, v := range overlay {
+		lower[strings.ToLower(k)] = v
+	}
+	for i, e := range r.Entries {
+		k := indexKey(e)
+		if d, ok := lower[k]; ok {
+			r.Entries[i].Description = []string{d}
+			used[k] = true
+		}
+	}
+	for k := range overlay {
+		if !used[strings.ToLower(k)] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// iso6393Import is the date RFC 5646 added the ISO 639-3 and 639-5 codes to the registry,
+// which until then only had the ISO 639-1 and 639-2 codes.
+var iso6393Import = time.Date(2009, 7, 29, 0, 0, 0, 0, time.UTC)
+
+// ISO639Coverage counts the language subtags coming from each part of ISO 639.
+//
+// Since the registry does not record the origin of subtags, this uses a heuristic:
+//   - set1 counts 2-letter subtags, which are ISO 639-1 codes;
+//   - set2 counts 3-letter subtags added before the ISO 639-3 import of 2009-07-29,
+//     which only came from ISO 639-2;
+//   - set3 counts the other 3-letter subtags, from ISO 639-3, or 639-5 for collections.
+//
+// Private-use ranges are not counted.
+func (r Registry) ISO639Coverage() (set1, set2, set3 int) {
+	for _, e := range r.Entries {
+		if e.Type != TypeLanguage || strings.Contains(e.Subtag, "..") {
+			continue
+		}
+		switch {
+		case len(e.Subtag) == 2:
+			set1++
+		case len(e.Subtag) == 3 && time.Time(e.Added).Before(iso6393Import):
+			set2++
+		case len(e.Subtag) == 3:
+			set3++
+		}
+	}
+	return set1, set2, set3
+}
+
+// ByAddedQuarter groups entries by the calendar quarter of their Added date, keyed like "2009-Q3",
+// in registry order. Entries without an Added date are excluded.
+func (r Registry) ByAddedQuarter() map[string][]Entry {
+	groups := make(map[string][]Entry)
+	for _, e := range r.Entries {
+		if e.Added.IsZero() {
+			continue
+		}
+		t := time.Time(e.Added)
+		key := fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+		groups[key] = append(groups[key], e)
+	}
+	return groups
+}
+
+// ByScope groups entries by their Scope, in registry order.
+// Entries without a scope, which are most of them, are grouped under "".
+func (r Registry) ByScope() map[Scope][]Entry {
+	groups := make(map[Scope][]Entry)
+	for _, e := range r.Entries {
+		groups[e.Scope] = append(groups[e.Scope], e)
+	}
+	return groups
+}
+
+// DeprecationTimeline maps years to the number of entries Deprecated during that year,
+// as for trend charts. Entries which are not deprecated are excluded.
+func (r Registry) DeprecationTimeline() map[int]int {
+	timeline := make(map[int]int)
+	for _, e := range r.Entries {
+		if e.Deprecated.IsZero() {
+			continue
+		}
+		timeline[time.Time(e.Deprecated).Year()]++
+	}
+	return timeline
+}
+
+// Subtags returns the Subtag of every entry, or Tag for grandfathered and redundant entries,
+// deduplicated and sorted, as for allow-lists.
+func (r Registry) Subtags() []string {
+	res := make([]string, 0, len(r.Entries))
+	for _, e := range r.Entries {
+		res = append(res, e.Key())
+	}
+	sort.Strings(res)
+	return slices.Compact(res)
+}
+
+// TypesInOrder returns the distinct entry types in the order they first appear,
+// which mirrors the grouping of entries by type in the IANA registry.
+func (r Registry) TypesInOrder() []Type {
+	var types []Type
+	seen := make(map[Type]bool)
+	for _, e := range r.Entries {
+		if !seen[e.Type] {
+			seen[e.Type] = true
+			types = append(types, e.Type)
+		}
+	}
+	return types
+}
+
+// AddedSince returns the entries Added on or after the given date, in registry order.
+func (r Registry) AddedSince(d Date) []Entry {
+	var res []Entry
+	for _, e := range r.Entries {
+		if !e.Added.IsZero() && !time.Time(e.Added).Before(time.Time(d)) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// DeprecatedBetween returns the entries Deprecated from start to end, both included, in registry order.
+func (r Registry) DeprecatedBetween(start, end Date) []Entry {
+	var res []Entry
+	for _, e := range r.Entries {
+		t := time.Time(e.Deprecated)
+		if !e.Deprecated.IsZero() && !t.Before(time.Time(start)) && !t.After(time.Time(end)) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
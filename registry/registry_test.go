@@ -0,0 +1,720 @@
+package registry
+
+import (
+	"iter"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testdataRegistry is an excerpt of the registry published by IANA, keeping its order and formatting.
+const testdataRegistry = "testdata/language-subtag-registry"
+
+// parseTestdata parses the testdataRegistry with opts.
+func parseTestdata(t *testing.T, opts Options) *Registry {
+	t.Helper()
+	f, err := os.Open(testdataRegistry)
+	if err != nil {
+		t.Fatalf("failed opening test registry: %v", err)
+	}
+	defer f.Close()
+	r, err := ParseWith(f, opts)
+	if err != nil {
+		t.Fatalf("failed parsing test registry: %v", err)
+	}
+	return r
+}
+
+// mustParse parses a registry from its text, like a test fixture.
+func mustParse(t *testing.T, text string) *Registry {
+	t.Helper()
+	r, err := Parse(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("failed parsing test registry: %v", err)
+	}
+	return r
+}
+
+// mustDate parses a YYYY-MM-DD date, panicking on errors.
+func mustDate(s string) Date {
+	var d Date
+	if err := d.Set(s); err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// keys returns the Key of each entry, for compact comparisons.
+func keys(entries []Entry) []string {
+	res := make([]string, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, e.Key())
+	}
+	return res
+}
+
+func TestRegistry_ReplacementGroups(t *testing.T) {
+	groups := parseTestdata(t, Options{}).ReplacementGroups()
+	tests := []struct {
+		preferred string
+		want      []string
+	}{
+		{"cmn", []string{"zh-guoyu", "zh-cmn"}}, // A consolidation, in registry order.
+		{"he", []string{"iw"}},
+		{"id", []string{"in"}},
+		{"MM", []string{"BU"}},
+		{"yue", nil}, // Only preferred by an extlang, which is not deprecated.
+		{"de", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.preferred, func(t *testing.T) {
+			if got := keys(groups[test.preferred]); !slices.Equal(got, test.want) {
+				t.Errorf("ReplacementGroups()[%q] = %q, want %q", test.preferred, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_NonASCIIDescriptions(t *testing.T) {
+	got := keys(parseTestdata(t, Options{}).NonASCIIDescriptions())
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"vo", true}, // Volapük
+		{"de", false},
+		{"1694acad", false}, // Only its Comments are accented.
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			if slices.Contains(got, test.key) != test.want {
+				t.Errorf("NonASCIIDescriptions() = %q, containing %s: %t, want %t", got, test.key, !test.want, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_ByInitial(t *testing.T) {
+	groups := parseTestdata(t, Options{}).ByInitial()
+	tests := []struct {
+		initial rune
+		want    []string
+	}{
+		{'c', []string{"cmn"}}, // Not the cmn extlang.
+		{'i', []string{"ia", "id", "in", "iw"}},
+		{'q', []string{"qaa..qtz"}},
+		{'z', []string{"zh", "zxx"}},
+		{'h', []string{"he"}}, // Not the Hans and Hant scripts.
+		{'b', nil},            // Not the BU region.
+	}
+	for _, test := range tests {
+		t.Run(string(test.initial), func(t *testing.T) {
+			if got := keys(groups[test.initial]); !slices.Equal(got, test.want) {
+				t.Errorf("ByInitial()[%q] = %q, want %q", test.initial, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_AddedInRelease(t *testing.T) {
+	entries := []Entry{
+		{Type: TypeLanguage, Subtag: "aaa", Added: mustDate("2023-03-16")},
+		{Type: TypeLanguage, Subtag: "bbb", Added: mustDate("2023-03-17")},
+		{Type: TypeLanguage, Subtag: "ccc", Added: mustDate("2009-07-29"), Deprecated: mustDate("2023-03-17")},
+		{Type: TypeRegion, Subtag: "DD", Added: mustDate("2023-03-17")},
+	}
+	tests := []struct {
+		fileDate string
+		want     []string
+	}{
+		{"2023-03-17", []string{"bbb", "DD"}}, // Not ccc, only deprecated on the File-Date.
+		{"2023-03-16", []string{"aaa"}},
+		{"2023-03-18", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.fileDate, func(t *testing.T) {
+			r := Registry{FileDate: mustDate(test.fileDate), Entries: entries}
+			if got := keys(r.AddedInRelease()); !slices.Equal(got, test.want) {
+				t.Errorf("AddedInRelease() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Specials(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	if got, want := keys(r.Specials()), []string{"mul", "und", "zxx"}; !slices.Equal(got, want) {
+		t.Errorf("Specials() = %q, want %q", got, want)
+	}
+	tests := []struct {
+		subtag  string
+		special bool
+	}{
+		{"und", true},
+		{"zxx", true},
+		{"de", false},
+		{"sgn", false},      // A collection.
+		{"qaa..qtz", false}, // Private use.
+	}
+	for _, test := range tests {
+		t.Run(test.subtag, func(t *testing.T) {
+			e, ok := r.Lookup(test.subtag, TypeLanguage)
+			if !ok {
+				t.Fatalf("missing language %s", test.subtag)
+			}
+			if e.IsSpecial() != test.special {
+				t.Errorf("IsSpecial() = %t, want %t", !test.special, test.special)
+			}
+			// Special subtags are valid, and canonical.
+			if test.special {
+				if err := r.ValidateTag(test.subtag); err != nil {
+					t.Errorf("ValidateTag() = %v, want nil", err)
+				}
+				if got, err := r.Canonicalize(test.subtag); got != test.subtag || err != nil {
+					t.Errorf("Canonicalize() = %q, %v, want %q", got, err, test.subtag)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_Reordered(t *testing.T) {
+	r := Registry{Entries: []Entry{
+		{Type: TypeLanguage, Subtag: "cmn"},
+		{Type: TypeLanguage, Subtag: "de"},
+		{Type: TypeLanguage, Subtag: "en"},
+		{Type: TypeExtlang, Subtag: "cmn"},
+		{Type: TypeRegion, Subtag: "FR"},
+		{Type: TypeRedundant, Tag: "zh-Hans"},
+	}}
+	tests := []struct {
+		name        string
+		order       []string
+		want        []string
+		wantUnknown []string
+	}{
+		{"empty", nil, []string{"cmn", "de", "en", "cmn", "FR", "zh-Hans"}, nil},
+		{"listed first", []string{"fr", "EN"}, []string{"FR", "en", "cmn", "de", "cmn", "zh-Hans"}, nil},
+		{"collisions in registry order", []string{"zh-hans", "cmn"}, []string{"zh-Hans", "cmn", "cmn", "de", "en", "FR"}, nil},
+		{"repeated", []string{"de", "de"}, []string{"de", "cmn", "en", "cmn", "FR", "zh-Hans"}, nil},
+		{"unknown", []string{"xx", "de", "yy"}, []string{"de", "cmn", "en", "cmn", "FR", "zh-Hans"}, []string{"xx", "yy"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, unknown := r.Reordered(test.order)
+			if !slices.Equal(keys(got), test.want) || !slices.Equal(unknown, test.wantUnknown) {
+				t.Errorf("Reordered(%q) = %q, %q, want %q, %q", test.order, keys(got), unknown, test.want, test.wantUnknown)
+			}
+		})
+	}
+}
+
+func TestRegistry_LanguagesSuppressing(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		script string
+		want   []string
+	}{
+		{"Latn", []string{"de", "en", "fr", "id", "in", "mo", "ro"}},
+		{"latn", []string{"de", "en", "fr", "id", "in", "mo", "ro"}},
+		{"Hebr", []string{"he", "iw"}},
+		{"Hans", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.script, func(t *testing.T) {
+			if got := keys(r.LanguagesSuppressing(test.script)); !slices.Equal(got, test.want) {
+				t.Errorf("LanguagesSuppressing(%q) = %q, want %q", test.script, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_MissingDescription(t *testing.T) {
+	r := Registry{Entries: []Entry{
+		{Type: TypeLanguage, Subtag: "de", Description: []string{"German"}},
+		{Type: TypeLanguage, Subtag: "xx"},
+		{Type: TypeRegion, Subtag: "XX"},
+		{Type: TypeGrandfathered, Tag: "i-xx"}, // Intentional on tag entries.
+		{Type: TypeRedundant, Tag: "xx-XX"},
+	}}
+	if got, want := keys(r.MissingDescription()), []string{"xx", "XX"}; !slices.Equal(got, want) {
+		t.Errorf("MissingDescription() = %q, want %q", got, want)
+	}
+	if got := parseTestdata(t, Options{}).MissingDescription(); got != nil {
+		t.Errorf("MissingDescription() = %q, want none on the IANA registry", keys(got))
+	}
+}
+
+func TestRegistry_All_Filtered(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	regions := func(e Entry) bool { return e.Type == TypeRegion }
+	tests := []struct {
+		name  string
+		seq   iter.Seq[Entry]
+		limit int // Break after that many entries.
+		want  []string
+	}{
+		{"all, break early", r.All(), 3, []string{"de", "en", "fr"}},
+		{"all", r.All(), len(r.Entries), keys(r.Entries)},
+		{"filtered, break early", r.Filtered(regions), 2, []string{"BU", "CN"}},
+		{"filtered", r.Filtered(regions), 100, []string{"BU", "CN", "DE", "FR", "MM", "TW", "US", "419"}},
+		{"filtered, none", r.Filtered(func(Entry) bool { return false }), 100, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for e := range test.seq {
+				got = append(got, e.Key())
+				if len(got) == test.limit {
+					break
+				}
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("iterated over %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_MacroTree(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tree := r.MacroTree()
+	tests := []struct {
+		macro string
+		want  []string
+	}{
+		{"zh", []string{"cmn", "yue"}}, // Languages only, not the extlangs.
+		{"ms", []string{"id", "in"}},
+		{"sh", nil}, // A macrolanguage without members in the excerpt.
+		{"de", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.macro, func(t *testing.T) {
+			members := tree[test.macro]
+			if got := keys(members); !slices.Equal(got, test.want) {
+				t.Errorf("MacroTree()[%q] = %q, want %q", test.macro, got, test.want)
+			}
+			for _, e := range members {
+				if e.Type != TypeLanguage || len(e.Description) == 0 {
+					t.Errorf("MacroTree()[%q] has incomplete entry %+v", test.macro, e)
+				}
+			}
+		})
+	}
+
+	var buf strings.Builder
+	if err := r.WriteMacroTree(&buf); err != nil {
+		t.Fatalf("WriteMacroTree() failed: %v", err)
+	}
+	want := "ms: Malay\n  id: Indonesian\n  in: Indonesian\nzh: Chinese\n  cmn: Mandarin Chinese\n  yue: Yue Chinese\n"
+	if buf.String() != want {
+		t.Errorf("WriteMacroTree() wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRegistry_ApplyOverlay(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	unknown := r.ApplyOverlay(map[string]string{
+		"qaa..qtz":   "Private languages of the team",
+		"I-KLINGON":  "Klingon, as a tag",
+		"zz":         "Unknown",
+		"aa":         "Also unknown",
+		"Qaaa..Qabx": "Private scripts",
+	})
+	if want := []string{"aa", "zz"}; !slices.Equal(unknown, want) {
+		t.Errorf("ApplyOverlay() = %q, want %q", unknown, want)
+	}
+	tests := []struct {
+		key       string
+		typ       Type
+		want      []string
+		wantAdded string
+	}{
+		{"qaa..qtz", TypeLanguage, []string{"Private languages of the team"}, "2005-10-16"},
+		{"Qaaa..Qabx", TypeScript, []string{"Private scripts"}, "2005-10-16"},
+		{"i-klingon", TypeGrandfathered, []string{"Klingon, as a tag"}, "1999-05-26"},
+		{"tlh", TypeLanguage, []string{"Klingon", "tlhIngan Hol"}, "2005-10-16"}, // Untouched.
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			e, ok := r.Lookup(test.key, test.typ)
+			if !ok {
+				t.Fatalf("missing %s %s", test.typ, test.key)
+			}
+			// Only the Description changes.
+			if !slices.Equal(e.Description, test.want) || e.Added.String() != test.wantAdded {
+				t.Errorf("entry is %+v, want description %q, added %s", e, test.want, test.wantAdded)
+			}
+		})
+	}
+}
+
+func TestRegistry_ISO639Coverage(t *testing.T) {
+	tests := []struct {
+		name                string
+		r                   Registry
+		want1, want2, want3 int
+	}{
+		// cmn and yue were added on the day of the ISO 639-3 import, the private-use range is excluded.
+		{"testdata", *parseTestdata(t, Options{}), 14, 6, 2},
+		{"not languages", Registry{Entries: []Entry{
+			{Type: TypeRegion, Subtag: "DE"},
+			{Type: TypeExtlang, Subtag: "yue", Added: mustDate("2009-07-29")},
+			{Type: TypeScript, Subtag: "Latn"},
+		}}, 0, 0, 0},
+		{"day before the import", Registry{Entries: []Entry{
+			{Type: TypeLanguage, Subtag: "aaa", Added: mustDate("2009-07-28")},
+		}}, 0, 1, 0},
+		{"empty", Registry{}, 0, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set1, set2, set3 := test.r.ISO639Coverage()
+			if set1 != test.want1 || set2 != test.want2 || set3 != test.want3 {
+				t.Errorf("ISO639Coverage() = %d, %d, %d, want %d, %d, %d", set1, set2, set3, test.want1, test.want2, test.want3)
+			}
+		})
+	}
+}
+
+func TestRegistry_ByAddedQuarter(t *testing.T) {
+	r := Registry{Entries: []Entry{
+		{Type: TypeLanguage, Subtag: "aaa", Added: mustDate("2009-07-29")},
+		{Type: TypeLanguage, Subtag: "bbb", Added: mustDate("2009-09-30")},
+		{Type: TypeLanguage, Subtag: "ccc", Added: mustDate("2009-10-01")},
+		{Type: TypeLanguage, Subtag: "ddd", Added: mustDate("2010-01-01")},
+		{Type: TypeLanguage, Subtag: "eee"}, // Not added: excluded.
+	}}
+	groups := r.ByAddedQuarter()
+	if len(groups) != 3 {
+		t.Errorf("ByAddedQuarter() has %d quarters, want 3", len(groups))
+	}
+	tests := []struct {
+		quarter string
+		want    []string
+	}{
+		{"2009-Q3", []string{"aaa", "bbb"}},
+		{"2009-Q4", []string{"ccc"}},
+		{"2010-Q1", []string{"ddd"}},
+		{"2009-Q2", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.quarter, func(t *testing.T) {
+			if got := keys(groups[test.quarter]); !slices.Equal(got, test.want) {
+				t.Errorf("ByAddedQuarter()[%q] = %q, want %q", test.quarter, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Subtags(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    []string
+	}{
+		{"sorted, with tags", []Entry{
+			{Type: TypeLanguage, Subtag: "de"},
+			{Type: TypeScript, Subtag: "Latn"},
+			{Type: TypeRegion, Subtag: "419"},
+			{Type: TypeRedundant, Tag: "zh-Hans"},
+		}, []string{"419", "Latn", "de", "zh-Hans"}},
+		{"deduplicated", []Entry{
+			{Type: TypeLanguage, Subtag: "cmn"},
+			{Type: TypeExtlang, Subtag: "cmn"},
+		}, []string{"cmn"}},
+		{"empty", nil, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := (Registry{Entries: test.entries}).Subtags(); !slices.Equal(got, test.want) {
+				t.Errorf("Subtags() = %q, want %q", got, test.want)
+			}
+		})
+	}
+	if got := parseTestdata(t, Options{}).Subtags(); len(got) != 47 { // cmn and yue are both languages and extlangs.
+		t.Errorf("Subtags() has %d subtags, want 47", len(got))
+	}
+}
+
+func TestRegistry_AddedSince(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		date string
+		want []string
+	}{
+		{"2009-12-09", []string{"alalc97"}}, // On the boundary.
+		{"2009-12-10", nil},
+		{"2009-07-29", []string{"cmn", "yue", "cmn", "yue", "alalc97"}},
+	}
+	for _, test := range tests {
+		t.Run(test.date, func(t *testing.T) {
+			if got := keys(r.AddedSince(mustDate(test.date))); !slices.Equal(got, test.want) {
+				t.Errorf("AddedSince(%s) = %q, want %q", test.date, got, test.want)
+			}
+		})
+	}
+	// Entries without an Added date are never returned.
+	if got := (Registry{Entries: []Entry{{Type: TypeLanguage, Subtag: "xx"}}}).AddedSince(Date{}); got != nil {
+		t.Errorf("AddedSince() = %q, want none", keys(got))
+	}
+}
+
+func TestDate_Set(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"2009-07-29", false},
+		{"2009-7-29", true},
+		{"29/07/2009", true},
+		{"2009-02-30", true},
+		{"", true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			var d Date
+			err := d.Set(test.value)
+			if (err != nil) != test.wantErr || (err == nil && d.String() != test.value) {
+				t.Errorf("Set(%q) = %v, giving %s, want error %t", test.value, err, d, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry_DeprecationTimeline(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Registry
+		want map[int]int
+	}{
+		{"testdata", *parseTestdata(t, Options{}), map[int]int{1989: 3, 2003: 1, 2004: 1, 2005: 1, 2008: 1, 2009: 2}},
+		{"not deprecated", Registry{Entries: []Entry{{Type: TypeLanguage, Subtag: "de", Added: mustDate("2005-10-16")}}}, map[int]int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.r.DeprecationTimeline(); !maps.Equal(got, test.want) {
+				t.Errorf("DeprecationTimeline() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_TypesInOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    []Type
+	}{
+		{"first appearance", []Entry{
+			{Type: TypeScript, Subtag: "Latn"},
+			{Type: TypeLanguage, Subtag: "de"},
+			{Type: TypeScript, Subtag: "Hans"},
+			{Type: TypeRegion, Subtag: "DE"},
+			{Type: TypeLanguage, Subtag: "fr"},
+		}, []Type{TypeScript, TypeLanguage, TypeRegion}},
+		{"empty", nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := (Registry{Entries: test.entries}).TypesInOrder(); !slices.Equal(got, test.want) {
+				t.Errorf("TypesInOrder() = %q, want %q", got, test.want)
+			}
+		})
+	}
+	want := []Type{TypeLanguage, TypeExtlang, TypeScript, TypeRegion, TypeVariant, TypeGrandfathered, TypeRedundant}
+	if got := parseTestdata(t, Options{}).TypesInOrder(); !slices.Equal(got, want) {
+		t.Errorf("TypesInOrder() = %q, want the IANA order %q", got, want)
+	}
+}
+
+func TestRegistry_CommentsMentioning(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		substr string
+		want   []string
+	}{
+		{"library of congress", []string{"alalc97"}}, // Case-insensitive.
+		{"French", []string{"1694acad"}},
+		{"Dictionnaire de l'académie", []string{"1694acad"}}, // Across a folded line.
+		{"r", []string{"sh", "1694acad", "alalc97"}},
+		{"RFC 5646", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.substr, func(t *testing.T) {
+			if got := keys(r.CommentsMentioning(test.substr)); !slices.Equal(got, test.want) {
+				t.Errorf("CommentsMentioning(%q) = %q, want %q", test.substr, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_ByScope(t *testing.T) {
+	groups := parseTestdata(t, Options{}).ByScope()
+	tests := []struct {
+		scope Scope
+		want  []string
+	}{
+		{ScopeCollection, []string{"sgn"}},
+		{ScopeMacrolanguage, []string{"ms", "sh", "zh"}},
+		{ScopeSpecial, []string{"mul", "und", "zxx"}},
+		{ScopePrivateUse, []string{"qaa..qtz"}},
+	}
+	scoped := 0
+	for _, test := range tests {
+		t.Run(test.scope.String(), func(t *testing.T) {
+			if got := keys(groups[test.scope]); !slices.Equal(got, test.want) {
+				t.Errorf("ByScope()[%q] = %q, want %q", test.scope, got, test.want)
+			}
+		})
+		scoped += len(test.want)
+	}
+	// All other entries are under the empty scope.
+	if got, want := len(groups[""]), 49-scoped; got != want {
+		t.Errorf("ByScope()[\"\"] has %d entries, want %d", got, want)
+	}
+	if len(groups) != len(tests)+1 {
+		t.Errorf("ByScope() has %d groups, want %d", len(groups), len(tests)+1)
+	}
+}
+
+func TestRegistry_Filter(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		name string
+		got  []Entry
+		want []string
+	}{
+		{"ByType region", r.ByType(TypeRegion), []string{"BU", "CN", "DE", "FR", "MM", "TW", "US", "419"}},
+		{"ByType extlang", r.ByType(TypeExtlang), []string{"cmn", "yue"}},
+		{"ByType unknown", r.ByType("dialect"), nil},
+		{"Deprecated", r.Deprecated(), []string{"in", "iw", "mo", "BU", "art-lojban", "i-klingon", "zh-guoyu", "zh-cmn", "zh-cmn-Hans"}},
+		{"Filter by scope", r.Filter(func(e *Entry) bool { return e.Scope == ScopeSpecial }), []string{"mul", "und", "zxx"}},
+		{"Filter by type and scope", r.Filter(func(e *Entry) bool {
+			return e.Type == TypeLanguage && e.Scope == ScopeMacrolanguage && e.Deprecated.IsZero()
+		}), []string{"ms", "sh", "zh"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := keys(test.got); !slices.Equal(got, test.want) {
+				t.Errorf("%s = %q, want %q", test.name, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_ChangedOnFileDate(t *testing.T) {
+	entries := []Entry{
+		{Type: TypeLanguage, Subtag: "aa", Added: mustDate("2005-10-16")},
+		{Type: TypeLanguage, Subtag: "bb", Added: mustDate("2023-08-02")},
+		{Type: TypeLanguage, Subtag: "cc", Added: mustDate("2005-10-16"), Deprecated: mustDate("2023-08-02")},
+		{Type: TypeLanguage, Subtag: "dd", Added: mustDate("2005-10-16"), Deprecated: mustDate("2023-08-01")},
+		{Type: TypeLanguage, Subtag: "ee", Added: mustDate("2023-08-03")},
+	}
+	tests := []struct {
+		name     string
+		fileDate Date
+		want     []string
+	}{
+		{"added or deprecated", mustDate("2023-08-02"), []string{"bb", "cc"}},
+		{"none", mustDate("2020-01-01"), nil},
+		{"no File-Date", Date{}, nil}, // Not the zero Deprecated of current entries.
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := Registry{FileDate: test.fileDate, Entries: entries}
+			if got := keys(r.ChangedOnFileDate()); !slices.Equal(got, test.want) {
+				t.Errorf("ChangedOnFileDate() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Extlangs(t *testing.T) {
+	extlangs := parseTestdata(t, Options{}).Extlangs()
+	tests := []struct {
+		extlang string
+		want    string
+		wantOK  bool
+	}{
+		{"cmn", "cmn", true},
+		{"yue", "yue", true},
+		{"zh", "", false}, // A language, not an extlang.
+	}
+	for _, test := range tests {
+		t.Run(test.extlang, func(t *testing.T) {
+			if got, ok := extlangs[test.extlang]; got != test.want || ok != test.wantOK {
+				t.Errorf("Extlangs()[%q] = %q, %t, want %q, %t", test.extlang, got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+	if len(extlangs) != 2 {
+		t.Errorf("Extlangs() = %q, want 2 extlangs", extlangs)
+	}
+	// Extlangs without a Preferred-Value map to the empty string.
+	r := Registry{Entries: []Entry{{Type: TypeExtlang, Subtag: "xxx", Prefix: []string{"zh"}}}}
+	if got, ok := r.Extlangs()["xxx"]; got != "" || !ok {
+		t.Errorf(`Extlangs()["xxx"] = %q, %t, want "", true`, got, ok)
+	}
+}
+
+func TestRegistry_DeprecatedBetween(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		name       string
+		start, end string
+		want       []string
+	}{
+		{"boundaries included", "2008-11-22", "2009-07-29", []string{"mo", "zh-cmn", "zh-cmn-Hans"}},
+		{"after the start", "2008-11-23", "2009-07-29", []string{"zh-cmn", "zh-cmn-Hans"}},
+		{"before the end", "2008-11-22", "2009-07-28", []string{"mo"}},
+		{"single day", "1989-01-01", "1989-01-01", []string{"in", "iw"}},
+		{"empty window", "2010-01-01", "2020-01-01", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := keys(r.DeprecatedBetween(mustDate(test.start), mustDate(test.end))); !slices.Equal(got, test.want) {
+				t.Errorf("DeprecatedBetween(%s, %s) = %q, want %q", test.start, test.end, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_SearchDescription(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"moldovan", []string{"mo", "ro"}}, // Not the first description of either.
+		{"YUE CHINESE", []string{"yue", "yue"}},
+		{"german", []string{"de", "DE", "1901", "1996"}},
+		{"simplified", []string{"Hans", "zh-cmn-Hans", "zh-Hans"}},
+		{"swiss german", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.q, func(t *testing.T) {
+			if got := keys(r.SearchDescription(test.q)); !slices.Equal(got, test.want) {
+				t.Errorf("SearchDescription(%q) = %q, want %q", test.q, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Filter_pointers(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	var calls int
+	got := r.Filter(func(e *Entry) bool {
+		if e != &r.Entries[calls] {
+			t.Fatalf("Filter() passed %p for entry %d, want %p in Entries", e, calls, &r.Entries[calls])
+		}
+		calls++
+		return e.Type == TypeExtlang
+	})
+	if calls != len(r.Entries) || !slices.Equal(keys(got), []string{"cmn", "yue"}) {
+		t.Errorf("Filter() = %q after %d calls, want [cmn yue] after %d", keys(got), calls, len(r.Entries))
+	}
+}
@@ -0,0 +1,34 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ValidateSchema checks each entry, serialized as by Entry.ToJSON, against the JSON Schema
+// in the file at schemaPath, returning all violations found, joined, or nil.
+func (r Registry) ValidateSchema(schemaPath string) error {
+	schema, err := jsonschema.Compile(schemaPath)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i, e := range r.Entries {
+		bs, err := e.ToJSON()
+		if err != nil {
+			errs = append(errs, entryError(i, e, "failed encoding to JSON: %v", err))
+			continue
+		}
+		var v any
+		if err = json.Unmarshal(bs, &v); err != nil {
+			errs = append(errs, entryError(i, e, "failed decoding its JSON: %v", err))
+			continue
+		}
+		if err = schema.Validate(v); err != nil {
+			errs = append(errs, entryError(i, e, "%v", err))
+		}
+	}
+	return errors.Join(errs...)
+}
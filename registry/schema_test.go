@@ -0,0 +1,50 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_ValidateSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entry.schema.json")
+	const schema = `{
+	"type": "object",
+	"required": ["type", "added", "description"],
+	"properties": {
+		"type": {"enum": ["language", "region"]},
+		"subtag": {"type": "string", "pattern": "^[a-zA-Z0-9]{2,8}$"},
+		"description": {"type": "array", "minItems": 1}
+	}
+}`
+	if err := os.WriteFile(path, []byte(schema), 0666); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		entry   Entry
+		wantErr string
+	}{
+		{"valid", Entry{Type: TypeLanguage, Subtag: "de", Description: []string{"German"}, Added: mustDate("2005-10-16")}, ""},
+		{"malformed subtag", Entry{Type: TypeLanguage, Subtag: "d e", Description: []string{"German"}, Added: mustDate("2005-10-16")},
+			"entry 0 (language d e)"},
+		{"missing description", Entry{Type: TypeRegion, Subtag: "DE", Added: mustDate("2005-10-16")}, "entry 0 (region DE)"},
+		{"rejected type", Entry{Type: TypeScript, Subtag: "Latn", Description: []string{"Latin"}, Added: mustDate("2005-10-16")},
+			"entry 0 (script Latn)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Registry{Entries: []Entry{test.entry}}.ValidateSchema(path)
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("ValidateSchema() = %v, want nil", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("ValidateSchema() = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+	if err := (Registry{}).ValidateSchema(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("ValidateSchema() with a missing schema = nil, want an error")
+	}
+}
@@ -0,0 +1,89 @@
+package registry
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Stats counts the entries in a registry.
+type Stats struct {
+	Total  int
+	Types  map[Type]int
+	Scopes map[Scope]int // Entries without a scope are counted under "".
+}
+
+// Stats counts the registry entries by Type and by Scope.
+func (r Registry) Stats() Stats {
+	s := Stats{
+		Total:  len(r.Entries),
+		Types:  make(map[Type]int),
+		Scopes: make(map[Scope]int),
+	}
+	for _, e := range r.Entries {
+		s.Types[e.Type]++
+		s.Scopes[e.Scope]++
+	}
+	return s
+}
+
+// CountByTypeScope counts the registry entries by (Type, Scope) pair, refining Stats
+// since Scope is only meaningful for some types. Entries without a scope are counted
+// under an empty Scope, like {"language", ""}.
+func (r Registry) CountByTypeScope() map[[2]string]int {
+	counts := make(map[[2]string]int)
+	for _, e := range r.Entries {
+		counts[[2]string{string(e.Type), string(e.Scope)}]++
+	}
+	return counts
+}
+
+// Percent returns count as a percentage of the total entries.
+func (s Stats) Percent(count int) float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return 100 * float64(count) / float64(s.Total)
+}
+
+// Write prints the stats as text, one count per line in key order,
+// with counts as percentages of the total if pct is set.
+func (s Stats) Write(w io.Writer, pct bool) error {
+	if _, err := fmt.Fprintf(w, "total: %d\n", s.Total); err != nil {
+		return err
+	}
+	types := make(map[string]int, len(s.Types))
+	for t, n := range s.Types {
+		types[string(t)] = n
+	}
+	scopes := make(map[string]int, len(s.Scopes))
+	for sc, n := range s.Scopes {
+		scopes[string(sc)] = n
+	}
+	for _, group := range []struct {
+		name   string
+		counts map[string]int
+	}{{"type", types}, {"scope", scopes}} {
+		keys := make([]string, 0, len(group.counts))
+		for k := range group.counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			label := k
+			if label == "" {
+				label = "(none)"
+			}
+			var err error
+			if pct {
+				_, err = fmt.Fprintf(w, "%s %s: %.1f%%\n", group.name, label, s.Percent(group.counts[k]))
+			} else {
+				_, err = fmt.Fprintf(w, "%s %s: %d\n", group.name, label, group.counts[k])
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
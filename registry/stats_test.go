@@ -0,0 +1,101 @@
+package registry
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStats_Write_pct(t *testing.T) {
+	s := parseTestdata(t, Options{}).Stats()
+	var buf bytes.Buffer
+	if err := s.Write(&buf, true); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	out := buf.String()
+	tests := []struct {
+		line string
+	}{
+		{"total: 49\n"},
+		{"type language: 46.9%\n"},
+		{"type extlang: 4.1%\n"},
+		{"type region: 16.3%\n"},
+		{"scope (none): 83.7%\n"},
+		{"scope macrolanguage: 6.1%\n"},
+	}
+	for _, test := range tests {
+		t.Run(strings.TrimSpace(test.line), func(t *testing.T) {
+			if !strings.Contains(out, test.line) {
+				t.Errorf("Write() = %q, want a %q line", out, test.line)
+			}
+		})
+	}
+	// The percentages of each group sum to 100, give or take the rounding.
+	sums, counts := make(map[string]float64), make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n")[1:] {
+		group, _, _ := strings.Cut(line, " ")
+		_, pct, _ := strings.Cut(line, ": ")
+		v, err := strconv.ParseFloat(strings.TrimSuffix(pct, "%"), 64)
+		if err != nil {
+			t.Fatalf("invalid percentage in %q: %v", line, err)
+		}
+		sums[group] += v
+		counts[group]++
+	}
+	for group, sum := range sums {
+		if math.Abs(sum-100) > 0.05*float64(counts[group]) {
+			t.Errorf("%s percentages sum to %.1f, want about 100", group, sum)
+		}
+	}
+}
+
+func TestStats_Percent(t *testing.T) {
+	tests := []struct {
+		total, count int
+		want         float64
+	}{
+		{49, 23, 100 * 23.0 / 49},
+		{4, 1, 25},
+		{0, 0, 0}, // Empty registries have no percentages.
+	}
+	for _, test := range tests {
+		t.Run(strconv.Itoa(test.count)+"/"+strconv.Itoa(test.total), func(t *testing.T) {
+			if got := (Stats{Total: test.total}).Percent(test.count); got != test.want {
+				t.Errorf("Percent() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_CountByTypeScope(t *testing.T) {
+	counts := parseTestdata(t, Options{}).CountByTypeScope()
+	tests := []struct {
+		typ   Type
+		scope Scope
+		want  int
+	}{
+		{TypeLanguage, ScopeMacrolanguage, 3},
+		{TypeLanguage, ScopeSpecial, 3},
+		{TypeLanguage, ScopeCollection, 1},
+		{TypeLanguage, ScopePrivateUse, 1},
+		{TypeLanguage, "", 15},
+		{TypeRegion, "", 8},
+		{TypeRegion, ScopeMacrolanguage, 0},
+	}
+	for _, test := range tests {
+		t.Run(string(test.typ)+"/"+string(test.scope), func(t *testing.T) {
+			if got := counts[[2]string{string(test.typ), string(test.scope)}]; got != test.want {
+				t.Errorf("CountByTypeScope()[{%s %s}] = %d, want %d", test.typ, test.scope, got, test.want)
+			}
+		})
+	}
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	if total != 49 {
+		t.Errorf("CountByTypeScope() counts %d entries, want 49", total)
+	}
+}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"sort"
+	"strings"
+)
+
+// Trie indexes the subtags and tags of a registry by their lower-cased bytes, for repeated
+// prefix queries like completions, faster than scanning the entries every time.
+type Trie struct {
+	children map[byte]*Trie
+	keys     []string // Subtags or tags ending at this node, sorted, like "DE" and "de".
+}
+
+// BuildTrie indexes the Subtags of the registry in a Trie.
+func (r Registry) BuildTrie() *Trie {
+	t := &Trie{}
+	for _, s := range r.Subtags() {
+		t.insert(s)
+	}
+	return t
+}
+
+// insert adds the subtag or tag s, case-insensitively.
+func (t *Trie) insert(s string) {
+	n := t
+	for _, c := range []byte(strings.ToLower(s)) {
+		if n.children == nil {
+			n.children = make(map[byte]*Trie)
+		}
+		child, ok := n.children[c]
+		if !ok {
+			child = &Trie{}
+			n.children[c] = child
+		}
+		n = child
+	}
+	n.keys = append(n.keys, s)
+}
+
+// Prefix returns the subtags and tags starting with p, compared case-insensitively,
+// in the order of their lower-cased forms.
+func (t *Trie) Prefix(p string) []string {
+	n := t
+	for _, c := range []byte(strings.ToLower(p)) {
+		if n = n.children[c]; n == nil {
+			return nil
+		}
+	}
+	var res []string
+	n.collect(&res)
+	return res
+}
+
+// collect appends the keys of the node and its descendants to res, in byte order.
+func (t *Trie) collect(res *[]string) {
+	*res = append(*res, t.keys...)
+	bs := make([]byte, 0, len(t.children))
+	for c := range t.children {
+		bs = append(bs, c)
+	}
+	sort.Slice(bs, func(i, j int) bool { return bs[i] < bs[j] })
+	for _, c := range bs {
+		t.children[c].collect(res)
+	}
+}
@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTrie_Prefix(t *testing.T) {
+	r := parseTestdata(t, Options{})
+	trie := r.BuildTrie()
+	// linear is the baseline, scanning all the subtags.
+	linear := func(p string) []string {
+		var res []string
+		for _, s := range r.Subtags() {
+			if strings.HasPrefix(strings.ToLower(s), strings.ToLower(p)) {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"zh", []string{"zh", "zh-cmn", "zh-cmn-Hans", "zh-guoyu", "zh-Hans", "zh-Hant"}},
+		{"DE", []string{"DE", "de"}}, // Case-insensitive.
+		{"h", []string{"Hans", "Hant", "he", "Hebr"}},
+		{"1", []string{"1694acad", "1901", "1996"}},
+		{"zz", nil},
+		{"", nil}, // All of them, checked against the baseline.
+	}
+	for _, test := range tests {
+		t.Run(test.prefix, func(t *testing.T) {
+			got := trie.Prefix(test.prefix)
+			if test.want != nil && !slices.Equal(got, test.want) {
+				t.Errorf("Prefix(%q) = %q, want %q", test.prefix, got, test.want)
+			}
+			// Only the order of keys differing by case is not that of their lower-cased forms.
+			if want := linear(test.prefix); !slices.Equal(slices.Sorted(slices.Values(got)), slices.Sorted(slices.Values(want))) {
+				t.Errorf("Prefix(%q) = %q, want the linear scan results %q", test.prefix, got, want)
+			}
+		})
+	}
+	if got := trie.Prefix(""); len(got) != len(r.Subtags()) {
+		t.Errorf(`Prefix("") has %d subtags, want all %d`, len(got), len(r.Subtags()))
+	}
+}
@@ -0,0 +1,322 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// entryError builds an error about the i-th entry of the registry.
+func entryError(i int, e Entry, format string, args ...any) error {
+	return fmt.Errorf("entry %d (%s %s): %s", i, e.Type, e.Key(), fmt.Sprintf(format, args...))
+}
+
+// Validate checks the registry for inconsistencies, returning all those found, joined, or nil.
+// These include the integrity problems of ValidateIntegrity, and those found by heuristics,
+// like the casing of subtags and the order of entries, which do not prevent using the registry.
+func (r Registry) Validate(opts Options) error {
+	errs := r.integrityErrors(opts)
+	errs = append(errs, r.checkOrphanedExtlangs()...)
+	errs = append(errs, r.checkTagDashes()...)
+	errs = append(errs, r.checkM49Regions()...)
+	errs = append(errs, r.checkVariantShapes()...)
+	errs = append(errs, r.checkFutureAdded()...)
+	errs = append(errs, r.checkPreferredTypes()...)
+	errs = append(errs, r.checkDuplicateDescriptions()...)
+	errs = append(errs, r.checkSubtagCasing()...)
+	errs = append(errs, r.checkOrder()...)
+	return errors.Join(errs...)
+}
+
+// ValidateIntegrity checks the registry for the problems making its entries unreliable:
+// missing required fields, unless opts.Lenient, and duplicate subtags or tags.
+// It returns all those found, joined, or nil.
+func (r Registry) ValidateIntegrity(opts Options) error {
+	return errors.Join(r.integrityErrors(opts)...)
+}
+
+// integrityErrors returns the errors of ValidateIntegrity, in registry order within each check.
+func (r Registry) integrityErrors(opts Options) []error {
+	var errs []error
+	if !opts.Lenient {
+		errs = append(errs, r.checkRequired()...)
+	}
+	errs = append(errs, r.checkDuplicateTags()...)
+	return append(errs, r.checkDuplicateSubtags()...)
+}
+
+// checkRequired reports entries lacking fields required by RFC 5646 §3.1.2.
+func (r Registry) checkRequired() []error {
+	var errs []error
+	for i, e := range r.Entries {
+		if e.Type == "" {
+			errs = append(errs, entryError(i, e, "missing Type"))
+		}
+		if e.Subtag == "" && e.Tag == "" {
+			errs = append(errs, entryError(i, e, "missing Subtag or Tag"))
+		}
+		if len(e.Description) == 0 {
+			errs = append(errs, entryError(i, e, "missing Description"))
+		}
+		if e.Added.IsZero() {
+			errs = append(errs, entryError(i, e, "missing Added"))
+		}
+	}
+	return errs
+}
+
+// checkOrphanedExtlangs reports extlang entries whose Prefix is not a language in the registry.
+func (r Registry) checkOrphanedExtlangs() []error {
+	languages := make(map[string]bool)
+	for _, e := range r.Entries {
+		if e.Type == TypeLanguage {
+			languages[strings.ToLower(e.Subtag)] = true
+		}
+	}
+	var errs []error
+	for i, e := range r.Entries {
+		if e.Type != TypeExtlang {
+			continue
+		}
+		for _, p := range e.Prefix {
+			if !languages[strings.ToLower(p)] {
+				errs = append(errs, entryError(i, e, "orphaned extlang: prefix %q is not a language", p))
+			}
+		}
+	}
+	return errs
+}
+
+// checkTagDashes reports grandfathered and redundant entries whose Tag does not contain a dash.
+func (r Registry) checkTagDashes() []error {
+	var errs []error
+	for i, e := range r.Entries {
+		if e.Type != TypeGrandfathered && e.Type != TypeRedundant {
+			continue
+		}
+		if !strings.Contains(e.Tag, "-") {
+			errs = append(errs, entryError(i, e, "tag %q does not contain a dash", e.Tag))
+		}
+	}
+	return errs
+}
+
+// checkDuplicateTags reports entries having the same Tag as a previous one, compared
+// case-insensitively, since tags identify grandfathered and redundant entries.
+func (r Registry) checkDuplicateTags() []error {
+	var errs []error
+	first := make(map[string]int)
+	for i, e := range r.Entries {
+		if e.Tag == "" {
+			continue
+		}
+		k := strings.ToLower(e.Tag)
+		if j, ok := first[k]; ok {
+			errs = append(errs, entryError(i, e, "duplicate tag, first used by entry %d", j))
+			continue
+		}
+		first[k] = i
+	}
+	return errs
+}
+
+// checkDuplicateSubtags reports entries having the same Subtag as a previous one of the same Type,
+// compared case-insensitively, like a language listed twice.
+func (r Registry) checkDuplicateSubtags() []error {
+	var errs []error
+	first := make(map[diffKey]int)
+	for i, e := range r.Entries {
+		if e.Subtag == "" {
+			continue
+		}
+		k := newDiffKey(e.Type, e.Subtag)
+		if j, ok := first[k]; ok {
+			errs = append(errs, entryError(i, e, "duplicate %s subtag, first used by entry %d", e.Type, j))
+			continue
+		}
+		first[k] = i
+	}
+	return errs
+}
+
+// checkM49Regions reports 3-character region subtags which are not UN M.49 codes,
+// i.e. integers from 001 to 999.
+func (r Registry) checkM49Regions() []error {
+	var errs []error
+	for i, e := range r.Entries {
+		if e.Type != TypeRegion || len(e.Subtag) != 3 {
+			continue
+		}
+		code, err := strconv.Atoi(e.Subtag)
+		switch {
+		case err != nil || strings.ContainsAny(e.Subtag, "+-"):
+			errs = append(errs, entryError(i, e, "3-character region %q is not numeric", e.Subtag))
+		case code < 1 || code > 999:
+			errs = append(errs, entryError(i, e, "region %q is out of the UN M.49 range", e.Subtag))
+		}
+	}
+	return errs
+}
+
+// checkVariantShapes reports variant subtags not matching the RFC 5646 §2.2.5 shape:
+// 5 to 8 letters or digits, or 4 starting with a digit, like "1901".
+func (r Registry) checkVariantShapes() []error {
+	var errs []error
+	for i, e := range r.Entries {
+		if e.Type == TypeVariant && !isVariant(e.Subtag) {
+			errs = append(errs, entryError(i, e, "variant %q is not 5 to 8 alphanumerics, or 4 starting with a digit", e.Subtag))
+		}
+	}
+	return errs
+}
+
+// checkFutureAdded reports entries Added after the File-Date of the registry, which
+// cannot have been added to it yet.
+func (r Registry) checkFutureAdded() []error {
+	if r.FileDate.IsZero() {
+		return nil
+	}
+	var errs []error
+	for i, e := range r.Entries {
+		if time.Time(e.Added).After(time.Time(r.FileDate)) {
+			errs = append(errs, entryError(i, e, "added on %s, after the File-Date %s", e.Added, r.FileDate))
+		}
+	}
+	return errs
+}
+
+// preferredTypes maps entry types to the type of entries their Preferred-Value designates.
+// Grandfathered and redundant entries may also prefer a full tag, which is not checked.
+var preferredTypes = map[Type]Type{
+	TypeExtlang:       TypeLanguage,
+	TypeGrandfathered: TypeLanguage,
+	TypeLanguage:      TypeLanguage,
+	TypeRedundant:     TypeLanguage,
+	TypeRegion:        TypeRegion,
+	TypeScript:        TypeScript,
+	TypeVariant:       TypeVariant,
+}
+
+// checkPreferredTypes reports entries whose Preferred-Value only designates entries of an incompatible type,
+// like a language preferring a region.
+func (r Registry) checkPreferredTypes() []error {
+	types := make(map[string][]string)
+	for _, e := range r.Entries {
+		k := indexKey(e)
+		types[k] = append(types[k], string(e.Type))
+	}
+	var errs []error
+	for i, e := range r.Entries {
+		expected, ok := preferredTypes[e.Type]
+		if e.PreferredValue == "" || !ok || (e.Tag != "" && strings.Contains(e.PreferredValue, "-")) {
+			continue
+		}
+		found := types[strings.ToLower(e.PreferredValue)]
+		if len(found) == 0 || slices.Contains(found, string(expected)) {
+			continue
+		}
+		errs = append(errs, entryError(i, e, "preferred value %q is a %s, not a %s",
+			e.PreferredValue, strings.Join(found, " and "), expected))
+	}
+	return errs
+}
+
+// checkDuplicateDescriptions reports entries listing the same Description more than once.
+func (r Registry) checkDuplicateDescriptions() []error {
+	var errs []error
+	for i, e := range r.Entries {
+		seen := make(map[string]bool, len(e.Description))
+		for _, d := range e.Description {
+			if seen[d] {
+				errs = append(errs, entryError(i, e, "duplicate description %q", d))
+			}
+			seen[d] = true
+		}
+	}
+	return errs
+}
+
+// recommendedCase returns a subtag with the casing BCP 47 recommends for its type:
+// upper case for regions, title case for scripts, lower case otherwise.
+// Both ends of a range like "Qaaa..Qabx" get the same casing.
+func recommendedCase(typ Type, subtag string) string {
+	if lo, hi, ok := strings.Cut(subtag, ".."); ok {
+		return recommendedCase(typ, lo) + ".." + recommendedCase(typ, hi)
+	}
+	switch typ {
+	case TypeRegion:
+		return strings.ToUpper(subtag)
+	case TypeScript:
+		if subtag == "" {
+			return subtag
+		}
+		return strings.ToUpper(subtag[:1]) + strings.ToLower(subtag[1:])
+	default:
+		return strings.ToLower(subtag)
+	}
+}
+
+// checkSubtagCasing reports entries whose Subtag does not use the recommended casing,
+// which the official registry always does.
+func (r Registry) checkSubtagCasing() []error {
+	var errs []error
+	for i, e := range r.Entries {
+		if e.Subtag == "" {
+			continue
+		}
+		if want := recommendedCase(e.Type, e.Subtag); want != e.Subtag {
+			errs = append(errs, entryError(i, e, "subtag %q should be cased as %q", e.Subtag, want))
+		}
+	}
+	return errs
+}
+
+// typeOrder is the order of the entry types in the IANA registry.
+var typeOrder = map[Type]int{
+	TypeLanguage:      1,
+	TypeExtlang:       2,
+	TypeScript:        3,
+	TypeRegion:        4,
+	TypeVariant:       5,
+	TypeGrandfathered: 6,
+	TypeRedundant:     7,
+}
+
+// sortKey returns the key by which IANA sorts entries within a type: the lower-cased
+// subtag or tag, ranges sorting by their start. Language, extlang, and region subtags
+// sort shorter first, like "zu" before "aaa" and "ZZ" before "001".
+func sortKey(e Entry) (length int, key string) {
+	key, _, _ = strings.Cut(indexKey(e), "..")
+	switch e.Type {
+	case TypeLanguage, TypeExtlang, TypeRegion:
+		return len(key), key
+	default:
+		return 0, key
+	}
+}
+
+// checkOrder reports entries appearing before the previous one in the canonical IANA order,
+// by type then subtag, which may reveal a merge error in an edited registry.
+func (r Registry) checkOrder() []error {
+	var errs []error
+	for i := 1; i < len(r.Entries); i++ {
+		prev, e := r.Entries[i-1], r.Entries[i]
+		pt, t := typeOrder[prev.Type], typeOrder[e.Type]
+		if pt == 0 || t == 0 {
+			continue
+		}
+		if t < pt {
+			errs = append(errs, entryError(i, e, "out of order: %s entries should precede %s entries", e.Type, prev.Type))
+			continue
+		}
+		pl, pk := sortKey(prev)
+		l, k := sortKey(e)
+		if t == pt && (l < pl || l == pl && k < pk) {
+			errs = append(errs, entryError(i, e, "out of order: should precede %q", prev.Key()))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,359 @@
+package registry
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// checkErrors compares the messages of the errors returned by a check with the wanted substrings,
+// one per error, in order.
+func checkErrors(t *testing.T, name string, errs []error, want []string) {
+	t.Helper()
+	if len(errs) != len(want) {
+		t.Fatalf("%s() = %v, want %d errors", name, errs, len(want))
+	}
+	for i, err := range errs {
+		if !strings.Contains(err.Error(), want[i]) {
+			t.Errorf("%s() error %d = %q, want it to contain %q", name, i, err, want[i])
+		}
+	}
+}
+
+func TestRegistry_Validate_lenient(t *testing.T) {
+	// A trimmed registry, with only some fields.
+	r := mustParse(t, `File-Date: 2023-08-02
+%%
+Type: language
+Subtag: de
+Description: German
+%%
+Type: language
+Subtag: fr
+Description: French
+`)
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{"strict", Options{}, "missing Added"},
+		{"lenient", Options{Lenient: true}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := r.Validate(test.opts)
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("Validate() = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry_Validate_testdata(t *testing.T) {
+	if err := parseTestdata(t, Options{}).Validate(Options{}); err != nil {
+		t.Errorf("Validate() = %v, want nil for an excerpt of the official registry", err)
+	}
+}
+
+func TestRegistry_ValidateIntegrity(t *testing.T) {
+	const (
+		de      = "%%\nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n"
+		fr      = "%%\nType: language\nSubtag: fr\nDescription: French\nAdded: 2005-10-16\n"
+		deUpper = "%%\nType: language\nSubtag: DE\nDescription: German\nAdded: 2005-10-16\n"
+	)
+	tests := []struct {
+		name          string
+		text          string
+		opts          Options
+		wantIntegrity []string // Also reported by Validate.
+		wantOther     string   // Only reported by Validate.
+	}{
+		{"valid", de + fr, Options{}, nil, ""},
+		{"duplicate subtag", de + fr + deUpper, Options{}, []string{"entry 2 (language DE): duplicate language subtag, first used by entry 0"}, ""},
+		{"duplicate tag", "%%\nType: grandfathered\nTag: i-klingon\nDescription: Klingon\nAdded: 1999-05-26\n" +
+			"%%\nType: grandfathered\nTag: i-klingon\nDescription: Klingon\nAdded: 1999-05-26\n", Options{}, []string{"entry 1 (grandfathered i-klingon): duplicate tag"}, ""},
+		{"missing field", de + "%%\nType: language\nSubtag: fr\nAdded: 2005-10-16\n", Options{}, []string{"entry 1 (language fr): missing Description"}, ""},
+		{"missing field, lenient", de + "%%\nType: language\nSubtag: fr\nAdded: 2005-10-16\n", Options{Lenient: true}, nil, ""},
+		{"casing", deUpper + fr, Options{}, nil, `subtag "DE" should be cased as "de"`},
+		{"order", fr + de, Options{}, nil, "out of order"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := mustParse(t, testRegistryHead+test.text)
+			checkErrors(t, "integrityErrors", r.integrityErrors(test.opts), test.wantIntegrity)
+			err := r.ValidateIntegrity(test.opts)
+			if (err != nil) != (len(test.wantIntegrity) > 0) {
+				t.Errorf("ValidateIntegrity() = %v, want %d errors", err, len(test.wantIntegrity))
+			}
+			err = r.Validate(test.opts)
+			for _, want := range append(slices.Clone(test.wantIntegrity), test.wantOther) {
+				if want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+					t.Errorf("Validate() = %v, want it to contain %q", err, want)
+				}
+			}
+			if err != nil && len(test.wantIntegrity) == 0 && test.wantOther == "" {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestRegistry_checkOrphanedExtlangs(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    []string
+	}{
+		{"prefix is a language", []Entry{
+			{Type: TypeLanguage, Subtag: "zh"},
+			{Type: TypeExtlang, Subtag: "yue", Prefix: []string{"zh"}},
+		}, nil},
+		{"prefix is missing", []Entry{
+			{Type: TypeLanguage, Subtag: "zh"},
+			{Type: TypeExtlang, Subtag: "yue", Prefix: []string{"zz"}},
+		}, []string{`entry 1 (extlang yue): orphaned extlang: prefix "zz" is not a language`}},
+		{"prefix is not a language", []Entry{
+			{Type: TypeRegion, Subtag: "ZH"},
+			{Type: TypeExtlang, Subtag: "yue", Prefix: []string{"zh"}},
+		}, []string{`prefix "zh" is not a language`}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkErrors(t, "checkOrphanedExtlangs", Registry{Entries: test.entries}.checkOrphanedExtlangs(), test.want)
+		})
+	}
+}
+
+func TestRegistry_checkTagDashes(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		want  []string
+	}{
+		{"grandfathered", Entry{Type: TypeGrandfathered, Tag: "i-klingon"}, nil},
+		{"redundant", Entry{Type: TypeRedundant, Tag: "zh-Hans"}, nil},
+		{"dashless grandfathered", Entry{Type: TypeGrandfathered, Tag: "klingon"}, []string{`tag "klingon" does not contain a dash`}},
+		{"dashless redundant", Entry{Type: TypeRedundant, Tag: "zhHans"}, []string{`tag "zhHans" does not contain a dash`}},
+		{"subtag", Entry{Type: TypeLanguage, Subtag: "de"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkErrors(t, "checkTagDashes", Registry{Entries: []Entry{test.entry}}.checkTagDashes(), test.want)
+		})
+	}
+}
+
+func TestRegistry_checkDuplicateTags(t *testing.T) {
+	const (
+		klingon = "%%\nType: grandfathered\nTag: i-klingon\nDescription: Klingon\nAdded: 1999-05-26\n"
+		hans    = "%%\nType: redundant\nTag: zh-Hans\nDescription: simplified Chinese\nAdded: 2003-05-30\n"
+	)
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"unique", klingon + hans, nil},
+		{"duplicate grandfathered", klingon + hans + klingon, []string{"entry 2 (grandfathered i-klingon): duplicate tag, first used by entry 0"}},
+		{"case-insensitive", hans + strings.Replace(hans, "zh-Hans", "ZH-hans", 1), []string{"entry 1 (redundant ZH-hans): duplicate tag, first used by entry 0"}},
+		{"across types", klingon + strings.Replace(klingon, "grandfathered", "redundant", 1), []string{"duplicate tag, first used by entry 0"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := mustParse(t, testRegistryHead+test.text)
+			checkErrors(t, "checkDuplicateTags", r.checkDuplicateTags(), test.want)
+		})
+	}
+}
+
+func TestRegistry_checkM49Regions(t *testing.T) {
+	tests := []struct {
+		subtag string
+		want   []string
+	}{
+		{"419", nil},
+		{"001", nil},
+		{"DE", nil}, // Not a 3-character region.
+		{"99a", []string{`3-character region "99a" is not numeric`}},
+		{"+99", []string{`3-character region "+99" is not numeric`}},
+		{"000", []string{`region "000" is out of the UN M.49 range`}},
+	}
+	for _, test := range tests {
+		t.Run(test.subtag, func(t *testing.T) {
+			r := Registry{Entries: []Entry{{Type: TypeRegion, Subtag: test.subtag}}}
+			checkErrors(t, "checkM49Regions", r.checkM49Regions(), test.want)
+		})
+	}
+}
+
+func TestRegistry_checkFutureAdded(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileDate string
+		added    string
+		want     []string
+	}{
+		{"before", "2023-08-02", "2005-10-16", nil},
+		{"on the File-Date", "2023-08-02", "2023-08-02", nil},
+		{"after", "2023-08-02", "2023-08-03", []string{"entry 0 (language xx): added on 2023-08-03, after the File-Date 2023-08-02"}},
+		{"no File-Date", "", "2023-08-03", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := Registry{Entries: []Entry{{Type: TypeLanguage, Subtag: "xx", Added: mustDate(test.added)}}}
+			if test.fileDate != "" {
+				r.FileDate = mustDate(test.fileDate)
+			}
+			checkErrors(t, "checkFutureAdded", r.checkFutureAdded(), test.want)
+		})
+	}
+}
+
+func TestRegistry_checkPreferredTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    []string
+	}{
+		{"language prefers a language", []Entry{
+			{Type: TypeLanguage, Subtag: "he"},
+			{Type: TypeLanguage, Subtag: "iw", PreferredValue: "he"},
+		}, nil},
+		{"language prefers a region", []Entry{
+			{Type: TypeRegion, Subtag: "HE"},
+			{Type: TypeLanguage, Subtag: "iw", PreferredValue: "he"},
+		}, []string{`entry 1 (language iw): preferred value "he" is a region, not a language`}},
+		{"collision with a compatible type", []Entry{
+			{Type: TypeLanguage, Subtag: "mm"},
+			{Type: TypeRegion, Subtag: "MM"},
+			{Type: TypeRegion, Subtag: "BU", PreferredValue: "MM"},
+		}, nil},
+		{"extlang prefers a language", []Entry{
+			{Type: TypeLanguage, Subtag: "yue"},
+			{Type: TypeExtlang, Subtag: "yue", PreferredValue: "yue"},
+		}, nil},
+		{"redundant prefers a tag", []Entry{
+			{Type: TypeRedundant, Tag: "zh-cmn-Hans", PreferredValue: "cmn-Hans"},
+		}, nil},
+		{"unknown preferred value", []Entry{
+			{Type: TypeLanguage, Subtag: "iw", PreferredValue: "he"},
+		}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkErrors(t, "checkPreferredTypes", Registry{Entries: test.entries}.checkPreferredTypes(), test.want)
+		})
+	}
+}
+
+func TestRegistry_checkDuplicateDescriptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		description []string
+		want        []string
+	}{
+		{"distinct", []string{"Romanian", "Moldavian", "Moldovan"}, nil},
+		{"repeated", []string{"Romanian", "Moldavian", "Romanian"}, []string{`entry 0 (language ro): duplicate description "Romanian"`}},
+		{"repeated twice", []string{"Romanian", "Romanian", "Romanian"}, []string{`"Romanian"`, `"Romanian"`}},
+		{"case differs", []string{"Romanian", "romanian"}, nil},
+		{"none", nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := Registry{Entries: []Entry{{Type: TypeLanguage, Subtag: "ro", Description: test.description}}}
+			checkErrors(t, "checkDuplicateDescriptions", r.checkDuplicateDescriptions(), test.want)
+		})
+	}
+}
+
+func TestRegistry_checkSubtagCasing(t *testing.T) {
+	tests := []struct {
+		entry Entry
+		want  []string
+	}{
+		{Entry{Type: TypeRegion, Subtag: "DE"}, nil},
+		{Entry{Type: TypeRegion, Subtag: "De"}, []string{`entry 0 (region De): subtag "De" should be cased as "DE"`}},
+		{Entry{Type: TypeRegion, Subtag: "419"}, nil},
+		{Entry{Type: TypeLanguage, Subtag: "DE"}, []string{`subtag "DE" should be cased as "de"`}},
+		{Entry{Type: TypeScript, Subtag: "Latn"}, nil},
+		{Entry{Type: TypeScript, Subtag: "LATN"}, []string{`subtag "LATN" should be cased as "Latn"`}},
+		{Entry{Type: TypeScript, Subtag: "Qaaa..Qabx"}, nil},
+		{Entry{Type: TypeScript, Subtag: "Qaaa..qabx"}, []string{`should be cased as "Qaaa..Qabx"`}},
+		{Entry{Type: TypeVariant, Subtag: "1994"}, nil},
+		{Entry{Type: TypeRedundant, Tag: "ZH-hans"}, nil}, // Tags are not checked.
+	}
+	for _, test := range tests {
+		t.Run(string(test.entry.Type)+"/"+test.entry.Key(), func(t *testing.T) {
+			checkErrors(t, "checkSubtagCasing", Registry{Entries: []Entry{test.entry}}.checkSubtagCasing(), test.want)
+		})
+	}
+}
+
+func TestRegistry_checkOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    []string
+	}{
+		{"canonical", []Entry{
+			{Type: TypeLanguage, Subtag: "zu"},
+			{Type: TypeLanguage, Subtag: "aaa"}, // Shorter subtags first.
+			{Type: TypeLanguage, Subtag: "qaa..qtz"},
+			{Type: TypeExtlang, Subtag: "aao"},
+			{Type: TypeScript, Subtag: "Adlm"},
+			{Type: TypeRegion, Subtag: "ZZ"},
+			{Type: TypeRegion, Subtag: "001"},
+			{Type: TypeVariant, Subtag: "1901"},
+			{Type: TypeGrandfathered, Tag: "art-lojban"},
+			{Type: TypeRedundant, Tag: "az-Arab"},
+		}, nil},
+		{"misordered subtags", []Entry{
+			{Type: TypeLanguage, Subtag: "fr"},
+			{Type: TypeLanguage, Subtag: "de"},
+			{Type: TypeLanguage, Subtag: "en"},
+		}, []string{`entry 1 (language de): out of order: should precede "fr"`}},
+		{"misordered types", []Entry{
+			{Type: TypeRegion, Subtag: "DE"},
+			{Type: TypeLanguage, Subtag: "de"},
+		}, []string{"entry 1 (language de): out of order: language entries should precede region entries"}},
+		{"case-insensitive", []Entry{
+			{Type: TypeRedundant, Tag: "zh-Hans"},
+			{Type: TypeRedundant, Tag: "zh-hant"},
+		}, nil},
+		{"unknown types are skipped", []Entry{
+			{Type: TypeRegion, Subtag: "DE"},
+			{Type: "other", Subtag: "zz"},
+			{Type: TypeRegion, Subtag: "FR"},
+		}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkErrors(t, "checkOrder", Registry{Entries: test.entries}.checkOrder(), test.want)
+		})
+	}
+}
+
+func TestRegistry_checkVariantShapes(t *testing.T) {
+	tests := []struct {
+		subtag string
+		want   []string
+	}{
+		{"1901", nil},     // 4 starting with a digit.
+		{"alalc97", nil},  // 5 to 8 alphanumerics.
+		{"1694acad", nil}, // 8.
+		{"abcd", []string{`variant "abcd" is not 5 to 8 alphanumerics, or 4 starting with a digit`}},
+		{"123", []string{`variant "123"`}},
+		{"abcdefghi", []string{`variant "abcdefghi"`}},
+		{"ab-cd", []string{`variant "ab-cd"`}},
+	}
+	for _, test := range tests {
+		t.Run(test.subtag, func(t *testing.T) {
+			r := Registry{Entries: []Entry{{Type: TypeVariant, Subtag: test.subtag}}}
+			checkErrors(t, "checkVariantShapes", r.checkVariantShapes(), test.want)
+		})
+	}
+}
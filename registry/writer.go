@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// WriteRegistry writes the registry in the IANA text format read by Parse,
+// folding long lines at DefaultFoldWidth.
+func (r Registry) WriteRegistry(w io.Writer) error {
+	return r.WriteRegistryWith(w, Options{})
+}
+
+// WriteRegistryWith is like WriteRegistry, folding long lines at opts.FoldWidth.
+//
+// Fields are written in the order used by IANA, and values longer than the width
+// are folded at spaces onto continuation lines indented by two spaces. Values with line breaks,
+// as parsed with Options.Faithful, keep them instead.
+func (r Registry) WriteRegistryWith(w io.Writer, opts Options) error {
+	bw := bufio.NewWriter(w)
+	width := opts.foldWidth()
+	writeField(bw, width, "File-Date", formatDate(r.FileDate))
+	for _, e := range r.Entries {
+		bw.WriteString("%%\n")
+		for _, f := range e.fields() {
+			writeField(bw, width, f.key, f.value)
+		}
+	}
+	return bw.Flush()
+}
+
+// field is a "Key: value" field of the IANA format.
+type field struct {
+	key, value string
+}
+
+// fields returns the non-empty fields of an entry, in the order used by IANA.
+func (e Entry) fields() []field {
+	var fs []field
+	add := func(key, value string) {
+		if value != "" {
+			fs = append(fs, field{key, value})
+		}
+	}
+	add("Type", string(e.Type))
+	add("Subtag", e.Subtag)
+	add("Tag", e.Tag)
+	for _, d := range e.Description {
+		add("Description", d)
+	}
+	add("Added", formatDate(e.Added))
+	add("Deprecated", formatDate(e.Deprecated))
+	add("Preferred-Value", e.PreferredValue)
+	for _, p := range e.Prefix {
+		add("Prefix", p)
+	}
+	add("Suppress-Script", e.SuppressScript.String())
+	add("Macrolanguage", e.MacroLanguage)
+	add("Scope", string(e.Scope))
+	add("Comments", e.Comments)
+	return fs
+}
+
+// formatDate formats a date like the registry, or as an empty string for a zero date.
+func formatDate(d Date) string {
+	if d.IsZero() {
+		return ""
+	}
+	return time.Time(d).Format("2006-01-02")
+}
+
+// writeField writes a "Key: value" field, folded at width, unless the value is empty.
+// Errors are left for the final bufio.Writer.Flush to report.
+func writeField(bw *bufio.Writer, width int, key, value string) {
+	if value == "" {
+		return
+	}
+	// Values keeping their line breaks, as parsed with Options.Faithful, are not folded again.
+	if strings.Contains(value, "\n") {
+		bw.WriteString(key + ": " + strings.ReplaceAll(value, "\n", "\n  ") + "\n")
+		return
+	}
+	words := strings.Split(value, " ")
+	line := key + ": " + words[0]
+	for _, word := range words[1:] {
+		if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+			bw.WriteString(line + "\n")
+			line = "  " + word
+			continue
+		}
+		line += " " + word
+	}
+	bw.WriteString(line + "\n")
+}
@@ -0,0 +1,136 @@
+package registry
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRegistry_WriteRegistryWith_foldWidth(t *testing.T) {
+	const description = "A long description of a language with many words, " +
+		"which does not fit on a single line of the registry at any of the tested widths"
+	r := Registry{FileDate: mustDate("2023-08-02"), Entries: []Entry{
+		{Type: TypeLanguage, Subtag: "xx", Description: []string{description}, Added: mustDate("2023-08-02")},
+	}}
+	tests := []struct {
+		name  string
+		width int
+		want  int
+	}{
+		{"default", 0, DefaultFoldWidth},
+		{"narrow", 30, 30},
+		{"wide", 100, 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := r.WriteRegistryWith(&buf, Options{FoldWidth: test.width}); err != nil {
+				t.Fatalf("WriteRegistryWith() failed: %v", err)
+			}
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			folded := 0
+			for _, line := range lines {
+				if n := utf8.RuneCountInString(line); n > test.want {
+					t.Errorf("line %q has %d runes, want at most %d", line, n, test.want)
+				}
+				if strings.HasPrefix(line, "  ") {
+					folded++
+				}
+			}
+			if folded == 0 {
+				t.Errorf("no continuation lines in %s", buf.String())
+			}
+			got := mustParse(t, buf.String())
+			if len(got.Entries) != 1 || !slices.Equal(got.Entries[0].Description, []string{description}) {
+				t.Errorf("re-parsed %+v, want description %q", got.Entries, description)
+			}
+		})
+	}
+}
+
+// checkRoundTrip checks that writing the registry parsed from data with opts and parsing it again
+// gives the same registry, and with identical, that the output is data, byte for byte.
+func checkRoundTrip(t *testing.T, data []byte, opts Options, identical bool) string {
+	t.Helper()
+	r, err := ParseWith(bytes.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := r.WriteRegistry(&buf); err != nil {
+		t.Fatalf("WriteRegistry() failed: %v", err)
+	}
+	got, err := ParseWith(bytes.NewReader(buf.Bytes()), opts)
+	if err != nil {
+		t.Fatalf("re-parsing failed: %v\n%s", err, buf.String())
+	}
+	if !got.FileDate.Equal(r.FileDate) || !reflect.DeepEqual(got.Entries, r.Entries) {
+		for i := range min(len(got.Entries), len(r.Entries)) {
+			if !reflect.DeepEqual(got.Entries[i], r.Entries[i]) {
+				t.Errorf("re-parsed entry %d = %+v, want %+v", i, got.Entries[i], r.Entries[i])
+			}
+		}
+		t.Fatalf("re-parsed %d entries from %s, want %d from %s", len(got.Entries), got.FileDate, len(r.Entries), r.FileDate)
+	}
+	if identical && !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("WriteRegistry() does not reproduce its input:\n%s", buf.String())
+	}
+	return buf.String()
+}
+
+func TestRegistry_WriteRegistry_roundTripExcerpt(t *testing.T) {
+	excerpt, err := os.ReadFile(testdataRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		opts      Options
+		identical bool // Whether the output is the testdata excerpt, byte for byte.
+	}{
+		{"unfolded values", Options{}, false},
+		{"faithful", Options{Faithful: true}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := checkRoundTrip(t, excerpt, test.opts, test.identical)
+			// Like in the excerpt of the official file, no line is longer than the default width.
+			for _, line := range strings.Split(out, "\n") {
+				if n := utf8.RuneCountInString(line); n > DefaultFoldWidth {
+					t.Errorf("line %q has %d runes, want at most %d", line, n, DefaultFoldWidth)
+				}
+			}
+		})
+	}
+}
+
+// TestRegistry_WriteRegistry_roundTripFile checks the round trip of a full registry file,
+// like one downloaded from Url, named by the IANA_REGISTRY_FILE environment variable,
+// since the testdata only holds an excerpt of it.
+func TestRegistry_WriteRegistry_roundTripFile(t *testing.T) {
+	path := os.Getenv("IANA_REGISTRY_FILE")
+	if path == "" {
+		t.Skip("IANA_REGISTRY_FILE does not name a registry file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		opts      Options
+		identical bool // Whether the output is the registry file, byte for byte.
+	}{
+		{"unfolded values", Options{}, false},
+		{"faithful", Options{Faithful: true}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkRoundTrip(t, data, test.opts, test.identical)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+// FormatSQLite is the -format writing the registry to an SQLite database file, which needs -o.
+const FormatSQLite = "sqlite"
+
+// sqliteSchema creates the tables of the SQLite format: the multi-valued Description
+// and Prefix fields have their own tables, referencing entries by id.
+const sqliteSchema = `
+CREATE TABLE registry (file_date TEXT NOT NULL);
+CREATE TABLE entries (
+	id              INTEGER PRIMARY KEY,
+	type            TEXT NOT NULL,
+	subtag          TEXT,
+	tag             TEXT,
+	added           TEXT,
+	deprecated      TEXT,
+	preferred_value TEXT,
+	macrolanguage   TEXT,
+	scope           TEXT,
+	suppress_script TEXT,
+	comments        TEXT
+);
+CREATE INDEX entries_subtag ON entries (subtag);
+CREATE TABLE descriptions (
+	entry_id    INTEGER NOT NULL REFERENCES entries (id),
+	position    INTEGER NOT NULL,
+	description TEXT NOT NULL
+);
+CREATE TABLE prefixes (
+	entry_id INTEGER NOT NULL REFERENCES entries (id),
+	position INTEGER NOT NULL,
+	prefix   TEXT NOT NULL
+);
+`
+
+// nullable maps empty strings to SQL NULL.
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// writeSQLite writes the registry to a new SQLite database at path, replacing any existing file.
+// Entry ids are their 1-based position in the registry.
+func writeSQLite(path string, r registry.Registry) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err = db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("failed creating tables: %w", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec(`INSERT INTO registry (file_date) VALUES (?)`, r.FileDate.String()); err != nil {
+		return err
+	}
+	insertEntry, err := tx.Prepare(`INSERT INTO entries (id, type, subtag, tag, added, deprecated,
+		preferred_value, macrolanguage, scope, suppress_script, comments) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	insertDescription, err := tx.Prepare(`INSERT INTO descriptions (entry_id, position, description) VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	insertPrefix, err := tx.Prepare(`INSERT INTO prefixes (entry_id, position, prefix) VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	for i, e := range r.Entries {
+		id := i + 1
+		if _, err = insertEntry.Exec(id, string(e.Type), nullable(e.Subtag), nullable(e.Tag),
+			nullable(e.Added.String()), nullable(e.Deprecated.String()), nullable(e.PreferredValue),
+			nullable(e.MacroLanguage), nullable(string(e.Scope)), nullable(e.SuppressScript.String()), nullable(e.Comments)); err != nil {
+			return entryError(i, e, "failed inserting", err)
+		}
+		for j, d := range e.Description {
+			if _, err = insertDescription.Exec(id, j, d); err != nil {
+				return entryError(i, e, "failed inserting description", err)
+			}
+		}
+		for j, p := range e.Prefix {
+			if _, err = insertPrefix.Exec(id, j, p); err != nil {
+				return entryError(i, e, "failed inserting prefix", err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// entryError reports an error about the i-th entry of the registry.
+func entryError(i int, e registry.Entry, msg string, err error) error {
+	return fmt.Errorf("entry %d (%s %s): %s: %w", i, e.Type, e.Key(), msg, err)
+}
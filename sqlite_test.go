@@ -0,0 +1,70 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+func TestWriteSQLite(t *testing.T) {
+	r, err := registry.Parse(strings.NewReader(testRegistry("2023-08-02") + `%%
+Type: variant
+Subtag: 1901
+Description: Traditional German orthography
+Added: 2005-10-16
+Prefix: de
+%%
+Type: redundant
+Tag: zh-cmn
+Description: Mandarin Chinese
+Description: Standard Chinese
+Added: 2005-07-15
+Deprecated: 2009-07-29
+Preferred-Value: cmn
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "registry.db")
+	// Writing twice replaces the previous database.
+	for range 2 {
+		if err = writeSQLite(path, *r); err != nil {
+			t.Fatalf("writeSQLite() failed: %v", err)
+		}
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var fileDate string
+	if err = db.QueryRow(`SELECT file_date FROM registry`).Scan(&fileDate); err != nil || fileDate != "2023-08-02" {
+		t.Errorf("file_date = %q, %v, want 2023-08-02", fileDate, err)
+	}
+	tests := []struct {
+		name  string
+		query string
+		arg   string
+		want  string
+	}{
+		{"subtag", `SELECT type || ' ' || added FROM entries WHERE subtag = ?`, "de", "language 2005-10-16"},
+		{"tag", `SELECT preferred_value || ' ' || deprecated FROM entries WHERE tag = ?`, "zh-cmn", "cmn 2009-07-29"},
+		{"null fields", `SELECT coalesce(tag, 'NULL') || ' ' || coalesce(deprecated, 'NULL') FROM entries WHERE subtag = ?`, "de", "NULL NULL"},
+		{"descriptions", `SELECT group_concat(description, ';') FROM (SELECT d.description FROM descriptions d
+			JOIN entries e ON e.id = d.entry_id WHERE e.tag = ? ORDER BY d.position)`, "zh-cmn", "Mandarin Chinese;Standard Chinese"},
+		{"prefixes", `SELECT p.prefix FROM prefixes p JOIN entries e ON e.id = p.entry_id WHERE e.subtag = ?`, "1901", "de"},
+		{"count", `SELECT count(*) || '' FROM entries WHERE ? != ''`, "all", "3"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got string
+			if err := db.QueryRow(test.query, test.arg).Scan(&got); err != nil || got != test.want {
+				t.Errorf("query for %q = %q, %v, want %q", test.arg, got, err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+// parseCache parses the cached registry, recording its source.
+func (f Fetcher) parseCache(cache registry.Cache, opts registry.Options) (*registry.Registry, error) {
+	rc, ok, err := cache.Load()
+	if err == nil && !ok {
+		err = errors.New("no cached registry")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	r, err := registry.ParseWith(rc, opts)
+	if err != nil {
+		return nil, err
+	}
+	r.Source = f.source(cache)
+	return r, nil
+}
+
+// watchRegistry refreshes the cache with refreshCache every interval until ctx is done,
+// calling emit with the cached registry the first time and each time its File-Date changes.
+//
+// Unless f.MaxAge is set, every refresh downloads the registry again, conditionally on the
+// validators of the cached one, so that unchanged registries are not transferred again.
+// Errors are logged and do not end the watch.
+func (f Fetcher) watchRegistry(ctx context.Context, cache registry.Cache, opts registry.Options, interval time.Duration, emit func(registry.Registry)) {
+	if f.MaxAge == 0 {
+		f.Refresh = true
+	}
+	var last registry.Date
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := refreshCache(ctx, f, cache)
+		var r *registry.Registry
+		if err == nil {
+			r, err = f.parseCache(cache, opts)
+		}
+		switch {
+		case err != nil:
+			if ctx.Err() == nil {
+				logErrors("Failed refreshing registry: %v", err)
+			}
+		case !r.FileDate.Equal(last):
+			last = r.FileDate
+			emit(*r)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fgm/iana_lang_registry_tools/registry"
+)
+
+// testRegistry returns the text of a minimal registry with the given File-Date.
+func testRegistry(fileDate string) string {
+	return "File-Date: " + fileDate + "\n%%\nType: language\nSubtag: de\nDescription: German\nAdded: 2005-10-16\n"
+}
+
+// newTestFetcher returns a Fetcher with its state next to a FileCache in a temporary directory.
+func newTestFetcher(t *testing.T) (Fetcher, registry.FileCache) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "registry.txt")
+	return Fetcher{StatePath: path}, registry.FileCache{Path: path}
+}
+
+func TestFetcher_watchRegistry(t *testing.T) {
+	versions := []struct{ etag, body string }{
+		{`"1"`, testRegistry("2023-01-01")},
+		{`"2"`, testRegistry("2023-02-01")},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		requests []string // The If-None-Match of each request.
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, req.Header.Get("If-None-Match"))
+		n := len(requests)
+		if n > 3 {
+			cancel()
+		}
+		v := versions[min(n, len(versions))-1]
+		if req.Header.Get("If-None-Match") == v.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", v.etag)
+		w.Write([]byte(v.body))
+	}))
+	defer srv.Close()
+
+	f, cache := newTestFetcher(t)
+	f.URLs = []string{srv.URL}
+	var emitted []string
+	f.watchRegistry(ctx, cache, registry.Options{}, 10*time.Millisecond, func(r registry.Registry) {
+		emitted = append(emitted, r.FileDate.String())
+	})
+
+	if want := []string{"2023-01-01", "2023-02-01"}; !slices.Equal(emitted, want) {
+		t.Errorf("emitted %q, want %q", emitted, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// The first download is unconditional, the next ones carry the ETag of the cached registry.
+	if want := []string{"", `"1"`, `"2"`}; len(requests) < 3 || !slices.Equal(requests[:3], want) {
+		t.Errorf("requests had If-None-Match %q, want %q first", requests, want)
+	}
+	meta, err := readMeta(f.StatePath + metaSuffix)
+	if err != nil || meta.ETag != `"2"` {
+		t.Errorf("cache metadata is %+v, %v, want ETag %q", meta, err, `"2"`)
+	}
+}